@@ -1,76 +1,241 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	vmap "utils/container/map"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-var db *sql.DB
+var (
+	// 按连接字符串缓存的数据库连接池
+	dbs = vmap.NewStrAnyMap(true)
 
-// db 是全局常量， 在init中处理没有办法传参数据库地址。
-// func init() {
-// 	// db, _ = sql.Open("mysql", "root:123456@tcp(139.198.189.162:13306)/FileBox?charset=utf8")
-// 	db, _ = sql.Open("mysql", connStr)
-// 	db.SetMaxOpenConns(1000)
-// 	err := db.Ping()
-// 	if err != nil {
-// 		fmt.Println("Failed to connect to mysql, err:" + err.Error())
-// 		os.Exit(1)
-// 	}
-// }
+	// 正在建立的连接，同一连接字符串的并发调用只连接一次
+	dialing   = make(map[string]*dialCall)
+	dialingMu sync.Mutex
 
-// connInit : 链接数据库
-func connInit(connStr string) {
-	db, _ = sql.Open("mysql", connStr)
-	db.SetMaxOpenConns(1000)
-	err := db.Ping()
+	// 数据库驱动名称
+	driverName = "mysql"
+)
+
+// dialCall : 进行中的一次连接，done关闭后db和err可读
+type dialCall struct {
+	done chan struct{}
+	db   *sql.DB
+	err  error
+}
+
+// Config : 数据库连接配置，零值的连接池参数保持database/sql的默认值
+type Config struct {
+	DSN             string
+	MaxOpen         int           // 最大打开连接数
+	MaxIdle         int           // 最大空闲连接数
+	ConnMaxLifetime time.Duration // 连接最长存活时间
+	ConnMaxIdleTime time.Duration // 连接最长空闲时间
+}
+
+// Open : 按配置打开数据库连接池并检查连通性
+func Open(config Config) (*sql.DB, error) {
+	return OpenContext(context.Background(), config)
+}
+
+// OpenContext : 与Open相同，ctx用于限制检查连通性的时间
+func OpenContext(ctx context.Context, config Config) (*sql.DB, error) {
+	db, err := sql.Open(driverName, config.DSN)
 	if err != nil {
-		fmt.Println("Failed to connect to mysql, err:" + err.Error())
-		os.Exit(1)
+		return nil, err
+	}
+	if config.MaxOpen > 0 {
+		db.SetMaxOpenConns(config.MaxOpen)
+	}
+	if config.MaxIdle > 0 {
+		db.SetMaxIdleConns(config.MaxIdle)
+	}
+	if config.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to connect to mysql: %v", err)
 	}
+	return db, nil
 }
 
-// DBConn : 返回数据库连接对象
-func DBConn(connStr string) *sql.DB {
-	if db == nil {
-		connInit(connStr)
+// connInit : 链接数据库
+func connInit(ctx context.Context, connStr string) (*sql.DB, error) {
+	return OpenContext(ctx, Config{DSN: connStr, MaxOpen: 1000})
+}
+
+// DBConn : 返回数据库连接对象，每个连接字符串对应独立的连接池。
+// 连接在全局锁外建立，不同连接字符串的连接互不阻塞
+func DBConn(connStr string) (*sql.DB, error) {
+	return dbConn(context.Background(), connStr)
+}
+
+// dbConn : 与DBConn相同，ctx用于限制建立连接和等待其他调用建立连接的时间
+func dbConn(ctx context.Context, connStr string) (*sql.DB, error) {
+	for {
+		if v := dbs.Get(connStr); v != nil {
+			return v.(*sql.DB), nil
+		}
+		dialingMu.Lock()
+		if v := dbs.Get(connStr); v != nil {
+			dialingMu.Unlock()
+			return v.(*sql.DB), nil
+		}
+		call, ok := dialing[connStr]
+		if !ok {
+			// 持有dialingMu跳出循环，由本次调用建立连接
+			break
+		}
+		dialingMu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// 其他调用的ctx结束导致连接失败时，使用自己的ctx重新连接
+		if ctx.Err() == nil && (call.err == context.Canceled || call.err == context.DeadlineExceeded) {
+			continue
+		}
+		return call.db, call.err
 	}
-	return db
+	call := &dialCall{done: make(chan struct{})}
+	dialing[connStr] = call
+	dialingMu.Unlock()
+
+	defer func() {
+		if call.db == nil && call.err == nil {
+			call.err = errors.New("failed to connect to mysql: connect panicked")
+		}
+		dialingMu.Lock()
+		if call.err == nil {
+			dbs.Set(connStr, call.db)
+		}
+		delete(dialing, connStr)
+		dialingMu.Unlock()
+		close(call.done)
+	}()
+	call.db, call.err = connInit(ctx, connStr)
+	return call.db, call.err
 }
 
 // ParseRows : 序列化返回结果
-func ParseRows(rows *sql.Rows) []map[string]interface{} {
-	columns, _ := rows.Columns()
+func ParseRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	records := make([]map[string]interface{}, 0)
+	err := eachRow(rows, func(record map[string]interface{}) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ParseRowsOrdered : 序列化返回结果，同时按查询顺序返回列名
+func ParseRowsOrdered(rows *sql.Rows) (columns []string, records []map[string]interface{}, err error) {
+	if columns, err = rows.Columns(); err != nil {
+		return nil, nil, err
+	}
+	if records, err = ParseRows(rows); err != nil {
+		return nil, nil, err
+	}
+	return columns, records, nil
+}
+
+// eachRow : 逐行扫描结果并调用fn，fn返回错误时停止
+func eachRow(rows *sql.Rows, fn func(record map[string]interface{}) error) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
 	scanArgs := make([]interface{}, len(columns))
 	values := make([]interface{}, len(columns))
 	for j := range values {
 		scanArgs[j] = &values[j]
 	}
 
-	record := make(map[string]interface{})
-	records := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		//将行数据保存到record字典
-		err := rows.Scan(scanArgs...)
-		checkErr(err)
+		if err := rows.Scan(scanArgs...); err != nil {
+			return err
+		}
 
+		record := make(map[string]interface{})
 		for i, col := range values {
-			if col != nil {
-				record[columns[i]] = col
+			record[columns[i]] = col
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ParseRowsTyped : 序列化返回结果，[]byte转换为string，整数和浮点列转换为int64和float64
+func ParseRowsTyped(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	records, err := ParseRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		for _, ct := range columnTypes {
+			if b, ok := record[ct.Name()].([]byte); ok {
+				record[ct.Name()] = convertBytes(b, ct.DatabaseTypeName())
 			}
 		}
-		records = append(records, record)
 	}
-	return records
+	return records, nil
+}
+
+func convertBytes(b []byte, dbType string) interface{} {
+	s := string(b)
+	switch strings.TrimPrefix(dbType, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case "FLOAT", "DOUBLE":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// Close : 关闭连接字符串对应的连接池，之后的DBConn会重新建立连接
+func Close(connStr string) error {
+	if v := dbs.Remove(connStr); v != nil {
+		return v.(*sql.DB).Close()
+	}
+	return nil
 }
 
-func checkErr(err error) {
+// Ping : 检查数据库连接是否可用
+func Ping(ctx context.Context, connStr string) error {
+	db, err := dbConn(ctx, connStr)
 	if err != nil {
-		log.Fatal(err)
-		panic(err)
+		return err
 	}
+	return db.PingContext(ctx)
 }
@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	vmap "utils/container/map"
+)
+
+const (
+	DEFAULT_GROUP_NAME = "default"   // 默认配置分组名称
+	DEFAULT_MYSQL_HOST = "127.0.0.1" // 未指定时的默认主机
+	DEFAULT_MYSQL_PORT = 3306        // 未指定时的默认端口
+)
+
+var (
+	// 按分组保存的数据库配置
+	configs = vmap.NewStrAnyMap(true)
+)
+
+// SetConfig : 设置分组的数据库配置，未传name时设置默认分组，并关闭该分组已建立的连接池
+func SetConfig(config Config, name ...string) {
+	group := DEFAULT_GROUP_NAME
+	if len(name) > 0 {
+		group = name[0]
+	}
+	configs.Set(group, config)
+	closeInstance(group)
+}
+
+// GetConfig : 返回分组的数据库配置，未传name时返回默认分组的配置
+func GetConfig(name ...string) (config Config, ok bool) {
+	group := DEFAULT_GROUP_NAME
+	if len(name) > 0 {
+		group = name[0]
+	}
+	if v := configs.Get(group); v != nil {
+		return v.(Config), true
+	}
+	return Config{}, false
+}
+
+// RemoveConfig : 删除分组的数据库配置，未传name时删除默认分组，并关闭该分组已建立的连接池
+func RemoveConfig(name ...string) {
+	group := DEFAULT_GROUP_NAME
+	if len(name) > 0 {
+		group = name[0]
+	}
+	configs.Remove(group)
+	closeInstance(group)
+}
+
+// DSNConfig : 组成数据库连接字符串的各项参数
+type DSNConfig struct {
+	User      string
+	Password  string
+	Host      string
+	Port      int
+	Database  string
+	Charset   string
+	Loc       string // time.Time使用的时区，如Local、UTC、Asia/Shanghai
+	ParseTime bool   // 是否将DATE和DATETIME列扫描为time.Time
+}
+
+// DSN : 按cfg生成mysql驱动的连接字符串，
+// 如user:password@tcp(host:port)/database?charset=utf8&loc=Local&parseTime=true
+func DSN(cfg DSNConfig) string {
+	host := cfg.Host
+	if host == "" {
+		host = DEFAULT_MYSQL_HOST
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = DEFAULT_MYSQL_PORT
+	}
+	params := url.Values{}
+	if cfg.Charset != "" {
+		params.Set("charset", cfg.Charset)
+	}
+	if cfg.Loc != "" {
+		params.Set("loc", cfg.Loc)
+	}
+	if cfg.ParseTime {
+		params.Set("parseTime", "true")
+	}
+	// 驱动以第一个':'和最后一个'@'拆分账号密码，密码无需转义
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s", cfg.User, cfg.Password, net.JoinHostPort(host, strconv.Itoa(port)), cfg.Database)
+	if len(params) > 0 {
+		dsn += "?" + params.Encode()
+	}
+	return dsn
+}
@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"database/sql"
+
+	vmap "utils/container/map"
+)
+
+var (
+	// 按分组缓存的数据库实例
+	instances = vmap.NewStrAnyMap(true)
+)
+
+// DB : 绑定配置分组的数据库连接池
+type DB struct {
+	*sql.DB
+	group string // 配置分组名称
+}
+
+// Group : 返回实例的配置分组名称
+func (db *DB) Group() string {
+	return db.group
+}
+
+// Close : 关闭连接池并移除实例，之后同一分组的Instance会重新建立连接。
+// 分组已缓存其他实例时不会移除
+func (db *DB) Close() error {
+	instances.LockFunc(func(m map[string]interface{}) {
+		if m[db.group] == db {
+			delete(m, db.group)
+		}
+	})
+	return db.DB.Close()
+}
+
+// closeInstance : 移除并关闭分组缓存的实例
+func closeInstance(group string) {
+	if v := instances.Remove(group); v != nil {
+		v.(*DB).DB.Close()
+	}
+}
+
+// Instance : 返回分组对应的数据库实例，未传name时返回默认分组的实例，
+// 分组未配置或连接失败时返回nil
+func Instance(name ...string) *DB {
+	group := DEFAULT_GROUP_NAME
+	if len(name) > 0 && name[0] != "" {
+		group = name[0]
+	}
+	v := instances.GetOrSetFuncLock(group, func() interface{} {
+		if config, ok := GetConfig(group); ok {
+			if db, err := Open(config); err == nil {
+				return &DB{DB: db, group: group}
+			}
+		}
+		return nil
+	})
+	if v != nil {
+		return v.(*DB)
+	}
+	return nil
+}
@@ -1,11 +1,22 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	// "fmt"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 type user struct {
@@ -15,16 +26,814 @@ type user struct {
 }
 
 type user1 struct {
-	Id   int `pk orm:"column(id)"`
+	Id   int `pk:"" orm:"column(id)"`
 	age  int
 	name string
 }
 
-func TestMain(t *testing.T) {
-	db := DBConn("cmpadmin:CMP_Zhu88jie@tcp(139.198.190.114:3306)/testing_v1.8.5_20191211?charset=utf8")
-	rows, _ := db.Query("select id from e_platform_node where cloud_resource_id = '/service/sites/43FC07EB/hosts/165' and is_deleted = 0")
-	ttt := ParseRows(rows)
-	// fmt.Println(string(ttt[0]["id"].([]uint8)))
-	// fmt.Println(ttt[0])
-	fmt.Println(string(ttt[0]["id"].([]uint8)))
+// TestDBConn connects to the mysql server of the DSN in the MYSQL_TEST_DSN environment variable,
+// eg: user:password@tcp(127.0.0.1:3306)/test
+func TestDBConn(t *testing.T) {
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_TEST_DSN is not set")
+	}
+	db, err := DBConn(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(dsn)
+	rows, err := db.Query("select 1 as id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	records, err := ParseRowsTyped(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0]["id"] != int64(1) {
+		t.Errorf("records = %v, want [map[id:1]]", records)
+	}
+}
+
+func TestParseRowsDistinctRecords(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select id, name from user").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(1, "tom").
+			AddRow(2, "jerry"))
+	rows, err := db.Query("select id, name from user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := ParseRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0]["id"] == records[1]["id"] || records[0]["name"] == records[1]["name"] {
+		t.Errorf("records share values: %v", records)
+	}
+}
+
+func TestParseRowsTyped(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select id, name, score from user").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("BIGINT", int64(0)),
+			sqlmock.NewColumn("name").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("score").OfType("DOUBLE", float64(0))).
+			AddRow([]byte("1"), []byte("tom"), []byte("9.5")))
+	rows, err := db.Query("select id, name, score from user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := ParseRowsTyped(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	if v, ok := records[0]["name"].(string); !ok || v != "tom" {
+		t.Errorf("name = %#v, want string \"tom\"", records[0]["name"])
+	}
+	if v, ok := records[0]["id"].(int64); !ok || v != 1 {
+		t.Errorf("id = %#v, want int64 1", records[0]["id"])
+	}
+	if v, ok := records[0]["score"].(float64); !ok || v != 9.5 {
+		t.Errorf("score = %#v, want float64 9.5", records[0]["score"])
+	}
+}
+
+func TestParseRowsNull(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select id, email from user").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "email"}).AddRow(1, nil))
+	rows, err := db.Query("select id, email from user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := ParseRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := records[0]["email"]
+	if !ok {
+		t.Fatal("NULL column missing from record")
+	}
+	if v != nil {
+		t.Errorf("email = %#v, want nil", v)
+	}
+}
+
+func TestParseRowsOrdered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	want := []string{"name", "id", "age", "email"}
+	mock.ExpectQuery("select name, id, age, email from user").WillReturnRows(
+		sqlmock.NewRows(want).AddRow("tom", 1, 18, "tom@example.com"))
+	rows, err := db.Query("select name, id, age, email from user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns, records, err := ParseRowsOrdered(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(columns) != fmt.Sprint(want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+	if len(records) != 1 || len(records[0]) != len(want) {
+		t.Errorf("records = %v", records)
+	}
+}
+
+func TestParseRowsError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rowErr := errors.New("bad row")
+	mock.ExpectQuery("select id from user").WillReturnRows(
+		sqlmock.NewRows([]string{"id"}).
+			AddRow(1).
+			AddRow(2).
+			RowError(1, rowErr))
+	rows, err := db.Query("select id from user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := ParseRows(rows)
+	if err != rowErr {
+		t.Errorf("ParseRows error = %v, want %v", err, rowErr)
+	}
+	if records != nil {
+		t.Errorf("ParseRows returned %v with an error", records)
+	}
+}
+
+func useMockDriver(t *testing.T) {
+	driverName = "sqlmock"
+	t.Cleanup(func() {
+		driverName = "mysql"
+	})
+}
+
+// mockConn registers a sqlmock connection under the test name and returns the DSN.
+func mockConn(t *testing.T) (string, sqlmock.Sqlmock) {
+	useMockDriver(t)
+	dsn := t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dbs.Remove(dsn)
+		mockDB.Close()
+	})
+	return dsn, mock
+}
+
+func TestDBConnPerDSN(t *testing.T) {
+	useMockDriver(t)
+	for _, dsn := range []string{"db_one", "db_two"} {
+		mockDB, _, err := sqlmock.NewWithDSN(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer mockDB.Close()
+	}
+	one, err := DBConn("db_one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	two, err := DBConn("db_two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if one == two {
+		t.Error("different DSNs share one *sql.DB")
+	}
+	if again, _ := DBConn("db_one"); again != one {
+		t.Error("same DSN returned a different *sql.DB")
+	}
+}
+
+func TestDBConnUnreachable(t *testing.T) {
+	db, err := DBConn("root:123456@tcp(127.0.0.1:1)/test?timeout=1s")
+	if err == nil {
+		t.Fatal("DBConn to an unreachable server returned nil error")
+	}
+	if db != nil {
+		t.Errorf("DBConn returned %v with an error", db)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectQuery("select name from user where id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("tom")).
+		RowsWillBeClosed()
+	records, err := Query(context.Background(), dsn, "select name from user where id = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || fmt.Sprint(records[0]["name"]) != "tom" {
+		t.Errorf("Query = %v", records)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueryCancelled(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectQuery("select name from user").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("tom"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Query(ctx, dsn, "select name from user"); err != context.Canceled {
+		t.Errorf("Query error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithTxCommit(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("update user").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err := WithTx(context.Background(), dsn, func(tx *sql.Tx) error {
+		_, err := tx.Exec("update user set age = 1")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithTxRollback(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	fnErr := errors.New("fn failed")
+	err := WithTx(context.Background(), dsn, func(tx *sql.Tx) error {
+		return fnErr
+	})
+	if err != fnErr {
+		t.Errorf("WithTx error = %v, want %v", err, fnErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithTxRollbackError(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback().WillReturnError(errors.New("connection lost"))
+	fnErr := errors.New("fn failed")
+	err := WithTx(context.Background(), dsn, func(tx *sql.Tx) error {
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("WithTx error = %v, want it to wrap %v", err, fnErr)
+	}
+}
+
+func TestWithTxPanic(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want boom", p)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}()
+	WithTx(context.Background(), dsn, func(tx *sql.Tx) error {
+		panic("boom")
+	})
+}
+
+func TestExec(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectExec("insert into user").
+		WithArgs("tom", 18).
+		WillReturnResult(sqlmock.NewResult(42, 1))
+	lastID, affected, err := Exec(context.Background(), dsn, "insert into user (name, age) values (?, ?)", "tom", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastID != 42 || affected != 1 {
+		t.Errorf("Exec = %d, %d, want 42, 1", lastID, affected)
+	}
+}
+
+func TestQueryStruct(t *testing.T) {
+	type User struct {
+		ID        int
+		Name      string `db:"user_name"`
+		Nickname  sql.NullString
+		Age       *int
+		CreatedAt string
+		Ignored   string `db:"-"`
+	}
+	dsn, mock := mockConn(t)
+	mock.ExpectQuery("select").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_name", "nickname", "age", "created_at", "ignored", "extra"}).
+			AddRow(1, "tom", "tommy", 18, "2020-01-01", "x", "y").
+			AddRow(2, "jerry", nil, nil, "2020-01-02", "x", "y"))
+	var users []User
+	if err := QueryStruct(context.Background(), dsn, &users, "select * from user"); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("got %d users, want 2", len(users))
+	}
+	tom, jerry := users[0], users[1]
+	if tom.ID != 1 || tom.Name != "tom" || tom.Nickname.String != "tommy" || tom.Age == nil || *tom.Age != 18 || tom.CreatedAt != "2020-01-01" {
+		t.Errorf("users[0] = %+v", tom)
+	}
+	if jerry.ID != 2 || jerry.Name != "jerry" || jerry.Nickname.Valid || jerry.Age != nil {
+		t.Errorf("users[1] = %+v", jerry)
+	}
+	if tom.Ignored != "" {
+		t.Errorf("ignored field set to %q", tom.Ignored)
+	}
+
+	var notSlice User
+	if err := QueryStruct(context.Background(), dsn, &notSlice, "select * from user"); err == nil {
+		t.Error("QueryStruct into a non-slice returned nil error")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dsn, _ := mockConn(t)
+	db, err := Open(Config{
+		DSN:             dsn,
+		MaxOpen:         20,
+		MaxIdle:         5,
+		ConnMaxLifetime: time.Minute,
+		ConnMaxIdleTime: 10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := db.Stats().MaxOpenConnections; n != 20 {
+		t.Errorf("MaxOpenConnections = %d, want 20", n)
+	}
+	db.Close()
+	db, err = DBConn(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := db.Stats().MaxOpenConnections; n != 1000 {
+		t.Errorf("DBConn MaxOpenConnections = %d, want 1000", n)
+	}
+}
+
+func TestInstance(t *testing.T) {
+	dsn, mock := mockConn(t)
+	SetConfig(Config{DSN: dsn, MaxOpen: 10}, "test")
+	defer RemoveConfig("test")
+
+	db := Instance("test")
+	if db == nil {
+		t.Fatal("Instance returned nil for a configured group")
+	}
+	if Instance("test") != db {
+		t.Error("Instance returned a different pointer for the same group")
+	}
+	if db.Group() != "test" {
+		t.Errorf("Group = %q, want %q", db.Group(), "test")
+	}
+	if Instance("missing") != nil {
+		t.Error("Instance returned non-nil for an unconfigured group")
+	}
+
+	mock.ExpectClose()
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if again := Instance("test"); again == nil || again == db {
+		t.Error("Instance after Close did not reconnect")
+	}
+}
+
+func TestSetConfigClosesInstance(t *testing.T) {
+	dsn, mock := mockConn(t)
+	SetConfig(Config{DSN: dsn}, "test")
+	defer RemoveConfig("test")
+
+	old := Instance("test")
+	if old == nil {
+		t.Fatal("Instance returned nil for a configured group")
+	}
+	mock.ExpectClose()
+	SetConfig(Config{DSN: dsn, MaxOpen: 10}, "test")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("SetConfig did not close the replaced instance: %v", err)
+	}
+
+	db := Instance("test")
+	if db == nil || db == old {
+		t.Fatal("Instance after SetConfig did not reconnect")
+	}
+	// Closing the replaced instance again keeps the new one.
+	old.Close()
+	if Instance("test") != db {
+		t.Error("Close of a replaced instance removed the new one")
+	}
+}
+
+func TestPing(t *testing.T) {
+	useMockDriver(t)
+	dsn := t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	defer dbs.Remove(dsn)
+
+	mock.ExpectPing()
+	mock.ExpectPing()
+	mock.ExpectPing().WillReturnError(errors.New("server gone"))
+	if err := Ping(context.Background(), dsn); err != nil {
+		t.Errorf("Ping = %v, want nil", err)
+	}
+	if err := Ping(context.Background(), dsn); err == nil {
+		t.Error("Ping of a lost server returned nil error")
+	}
+	if err := Ping(context.Background(), "root:123456@tcp(127.0.0.1:1)/test?timeout=1s"); err == nil {
+		t.Error("Ping of an unreachable server returned nil error")
+	}
+}
+
+func TestClose(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectClose()
+	first, err := DBConn(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Close(dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Ping(); err == nil {
+		t.Error("closed pool still pings")
+	}
+	second, err := DBConn(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("DBConn after Close returned the closed pool")
+	}
+	if err := Close("never opened"); err != nil {
+		t.Errorf("Close of an unknown DSN = %v, want nil", err)
+	}
+}
+
+// gateDriver blocks opening a connection until the gate is closed, then fails it.
+type gateDriver struct {
+	gate  chan struct{}
+	opens int32
+}
+
+func (d *gateDriver) Open(name string) (driver.Conn, error) {
+	atomic.AddInt32(&d.opens, 1)
+	<-d.gate
+	return nil, errors.New("gate closed")
+}
+
+var testGate = &gateDriver{}
+
+func init() {
+	sql.Register("gate", testGate)
+}
+
+func TestDBConnConcurrent(t *testing.T) {
+	cachedDSN, _ := mockConn(t)
+	cached, err := DBConn(cachedDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	driverName = "gate"
+	testGate.gate = make(chan struct{})
+	atomic.StoreInt32(&testGate.opens, 0)
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, 2)
+	)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = DBConn("slow")
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// A slow connect must not block the connections of other DSNs.
+	done := make(chan struct{})
+	go func() {
+		if db, _ := DBConn(cachedDSN); db != cached {
+			t.Error("cached DSN returned a different *sql.DB")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("DBConn of a cached DSN blocked by another DSN connecting")
+	}
+
+	close(testGate.gate)
+	wg.Wait()
+	if n := atomic.LoadInt32(&testGate.opens); n != 1 {
+		t.Errorf("concurrent DBConn connected %d times, want 1", n)
+	}
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("DBConn #%d through a failed connect returned nil error", i)
+		}
+	}
+	if dbs.Contains("slow") {
+		t.Error("failed connection was cached")
+	}
+}
+
+func TestDBConnContext(t *testing.T) {
+	// The server accepts connections but never sends the handshake.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	dsn := fmt.Sprintf("root:123456@tcp(%s)/test", l.Addr())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := Query(ctx, dsn, "select 1"); err == nil {
+		t.Error("Query of a silent server returned nil error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Query ignored the context deadline for %v", elapsed)
+	}
+}
+
+func TestDBConnWaitContext(t *testing.T) {
+	useMockDriver(t)
+	driverName = "gate"
+	testGate.gate = make(chan struct{})
+	connected := make(chan struct{})
+	go func() {
+		DBConn("slow")
+		close(connected)
+	}()
+	defer func() {
+		close(testGate.gate)
+		<-connected
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// A caller waiting for another connect gives up when its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := Ping(ctx, "slow"); err != context.DeadlineExceeded {
+		t.Errorf("Ping waiting for a slow connect = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	row := map[string]interface{}{"name": "tom", "age": 18, "email": "tom@example.com"}
+	query, args := buildInsert("user", row)
+	wantQuery := "INSERT INTO `user` (`age`, `email`, `name`) VALUES (?, ?, ?)"
+	if query != wantQuery {
+		t.Errorf("query = %q, want %q", query, wantQuery)
+	}
+	if fmt.Sprint(args) != "[18 tom@example.com tom]" {
+		t.Errorf("args = %v", args)
+	}
+
+	dsn, mock := mockConn(t)
+	mock.ExpectExec(regexp.QuoteMeta(wantQuery)).
+		WithArgs(18, "tom@example.com", "tom").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	id, err := Insert(context.Background(), dsn, "user", row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 7 {
+		t.Errorf("Insert id = %d, want 7", id)
+	}
+	if _, err := Insert(context.Background(), dsn, "user", nil); err == nil {
+		t.Error("Insert of an empty row returned nil error")
+	}
+}
+
+func TestInsertSchemaTable(t *testing.T) {
+	query, _ := buildInsert("shop.orders", map[string]interface{}{"id": 1})
+	if want := "INSERT INTO `shop`.`orders` (`id`) VALUES (?)"; query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+	if quoted := quoteIdentifier("we`ird"); quoted != "`we``ird`" {
+		t.Errorf("quoteIdentifier = %q, want %q", quoted, "`we``ird`")
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+	query, args, err := buildPage("select * from user where age > ?", 3, 20, []interface{}{18})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "select * from user where age > ? LIMIT ? OFFSET ?" {
+		t.Errorf("query = %q", query)
+	}
+	if fmt.Sprint(args) != "[18 20 40]" {
+		t.Errorf("args = %v, want [18 20 40]", args)
+	}
+
+	dsn, mock := mockConn(t)
+	mock.ExpectQuery("select id from user LIMIT").
+		WithArgs(10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	records, err := QueryPage(context.Background(), dsn, "select id from user", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Errorf("got %d records, want 2", len(records))
+	}
+
+	for _, c := range [][2]int{{0, 10}, {-1, 10}, {1, 0}, {1, -5}} {
+		if _, err := QueryPage(context.Background(), dsn, "select id from user", c[0], c[1]); err == nil {
+			t.Errorf("QueryPage(page=%d, pageSize=%d) returned nil error", c[0], c[1])
+		}
+	}
+}
+
+func TestQueryEach(t *testing.T) {
+	dsn, mock := mockConn(t)
+	rows := sqlmock.NewRows([]string{"id"})
+	for i := 1; i <= 1000; i++ {
+		rows.AddRow(i)
+	}
+	mock.ExpectQuery("select id from user").WillReturnRows(rows).RowsWillBeClosed()
+
+	stop := errors.New("stop")
+	count := 0
+	err := QueryEach(context.Background(), dsn, "select id from user", func(row map[string]interface{}) error {
+		count++
+		if row["id"] == int64(500) {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("QueryEach error = %v, want %v", err, stop)
+	}
+	if count != 500 {
+		t.Errorf("processed %d rows, want 500", count)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDSN(t *testing.T) {
+	dsn := DSN(DSNConfig{
+		User:      "root",
+		Password:  "p@ss:w/rd",
+		Host:      "db.local",
+		Port:      3307,
+		Database:  "test",
+		Charset:   "utf8mb4",
+		Loc:       "Asia/Shanghai",
+		ParseTime: true,
+	})
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("ParseDSN(%q): %v", dsn, err)
+	}
+	if cfg.User != "root" || cfg.Passwd != "p@ss:w/rd" {
+		t.Errorf("credentials = %q, %q", cfg.User, cfg.Passwd)
+	}
+	if cfg.Addr != "db.local:3307" || cfg.DBName != "test" {
+		t.Errorf("addr = %q, db = %q", cfg.Addr, cfg.DBName)
+	}
+	if !cfg.ParseTime || cfg.Loc.String() != "Asia/Shanghai" {
+		t.Errorf("parseTime = %v, loc = %v", cfg.ParseTime, cfg.Loc)
+	}
+	if cfg.Params["charset"] != "utf8mb4" {
+		t.Errorf("charset = %q", cfg.Params["charset"])
+	}
+
+	if dsn := DSN(DSNConfig{User: "root", Database: "test"}); dsn != "root:@tcp(127.0.0.1:3306)/test" {
+		t.Errorf("default DSN = %q", dsn)
+	}
+}
+
+func TestWithTxRetry(t *testing.T) {
+	dsn, mock := mockConn(t)
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	calls := 0
+	err := WithTxRetry(context.Background(), dsn, 3, func(tx *sql.Tx) error {
+		calls++
+		if calls <= 2 {
+			return deadlock
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithTxRetryGivesUp(t *testing.T) {
+	dsn, mock := mockConn(t)
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+	calls := 0
+	err := WithTxRetry(context.Background(), dsn, 2, func(tx *sql.Tx) error {
+		calls++
+		return deadlock
+	})
+	if err != deadlock || calls != 2 {
+		t.Errorf("WithTxRetry = %v after %d calls, want %v after 2", err, calls, deadlock)
+	}
+}
+
+func TestWithTxRetryNotRetryable(t *testing.T) {
+	dsn, mock := mockConn(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	dup := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	calls := 0
+	err := WithTxRetry(context.Background(), dsn, 3, func(tx *sql.Tx) error {
+		calls++
+		return dup
+	})
+	if err != dup || calls != 1 {
+		t.Errorf("WithTxRetry = %v after %d calls, want %v after 1", err, calls, dup)
+	}
 }
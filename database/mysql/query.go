@@ -0,0 +1,193 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"utils/text/str"
+)
+
+// Query : 执行查询并序列化返回结果
+func Query(ctx context.Context, connStr, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	db, err := dbConn(ctx, connStr)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return ParseRows(rows)
+}
+
+// QueryEach : 执行查询并逐行调用fn，不会一次性加载全部结果，fn返回错误时停止并返回该错误
+func QueryEach(ctx context.Context, connStr, query string, fn func(row map[string]interface{}) error, args ...interface{}) error {
+	db, err := dbConn(ctx, connStr)
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return eachRow(rows, fn)
+}
+
+// QueryPage : 分页查询，page从1开始
+func QueryPage(ctx context.Context, connStr, baseQuery string, page, pageSize int, args ...interface{}) ([]map[string]interface{}, error) {
+	query, args, err := buildPage(baseQuery, page, pageSize, args)
+	if err != nil {
+		return nil, err
+	}
+	return Query(ctx, connStr, query, args...)
+}
+
+// buildPage : 在查询后追加LIMIT和OFFSET占位符
+func buildPage(baseQuery string, page, pageSize int, args []interface{}) (string, []interface{}, error) {
+	if page < 1 {
+		return "", nil, fmt.Errorf("invalid page %d, must be >= 1", page)
+	}
+	if pageSize <= 0 {
+		return "", nil, fmt.Errorf("invalid page size %d, must be > 0", pageSize)
+	}
+	args = append(args[:len(args):len(args)], pageSize, (page-1)*pageSize)
+	return baseQuery + " LIMIT ? OFFSET ?", args, nil
+}
+
+// Exec : 执行INSERT/UPDATE/DELETE等语句，返回最后插入的ID和影响的行数
+func Exec(ctx context.Context, connStr, query string, args ...interface{}) (lastID, rowsAffected int64, err error) {
+	db, err := dbConn(ctx, connStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	if lastID, err = result.LastInsertId(); err != nil {
+		return 0, 0, err
+	}
+	if rowsAffected, err = result.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+	return lastID, rowsAffected, nil
+}
+
+// Insert : 按map插入一行数据，返回最后插入的ID
+func Insert(ctx context.Context, connStr, table string, row map[string]interface{}) (int64, error) {
+	if len(row) == 0 {
+		return 0, errors.New("insert row cannot be empty")
+	}
+	query, args := buildInsert(table, row)
+	lastID, _, err := Exec(ctx, connStr, query, args...)
+	return lastID, err
+}
+
+// buildInsert : 生成参数化的INSERT语句，列按名称排序
+func buildInsert(table string, row map[string]interface{}) (string, []interface{}) {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+		args[i] = row[column]
+	}
+	query := "INSERT INTO " + quoteIdentifier(table) +
+		" (" + strings.Join(quoted, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	return query, args
+}
+
+// quoteIdentifier : 用反引号引用表名或列名，以'.'分隔的库名和表名分别引用，如shop.orders
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// QueryStruct : 执行查询并将结果扫描到dest，dest为结构体切片的指针。
+// 列名通过字段的db标签匹配，没有标签时使用字段名的蛇形命名
+func QueryStruct(ctx context.Context, connStr string, dest interface{}, query string, args ...interface{}) error {
+	sliceValue := reflect.ValueOf(dest)
+	if sliceValue.Kind() != reflect.Ptr || sliceValue.Elem().Kind() != reflect.Slice {
+		return errors.New("dest must be a pointer to a slice of structs")
+	}
+	sliceValue = sliceValue.Elem()
+	elemType := sliceValue.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return errors.New("dest must be a pointer to a slice of structs")
+	}
+
+	db, err := dbConn(ctx, connStr)
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	fields := structFields(structType)
+	for rows.Next() {
+		elem := reflect.New(structType)
+		scanArgs := make([]interface{}, len(columns))
+		for i, col := range columns {
+			if index, ok := fields[strings.ToLower(col)]; ok {
+				scanArgs[i] = elem.Elem().Field(index).Addr().Interface()
+			} else {
+				scanArgs[i] = new(interface{})
+			}
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return err
+		}
+		if elemType.Kind() == reflect.Ptr {
+			sliceValue.Set(reflect.Append(sliceValue, elem))
+		} else {
+			sliceValue.Set(reflect.Append(sliceValue, elem.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// structFields : 返回小写列名到结构体字段下标的映射
+func structFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("db")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = str.SnakeCase(field.Name)
+		}
+		fields[strings.ToLower(name)] = i
+	}
+	return fields
+}
@@ -1,7 +1,14 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	vmap "utils/container/map"
@@ -12,9 +19,11 @@ import (
 
 // Redis client.
 type Redis struct {
-	pool   *redis.Pool // Underlying connection pool.
-	group  string      // Configuration group.
-	config Config      // Configuration.
+	pool     *connPool // Underlying connection pool.
+	cluster  *cluster  // Cluster client, only set in cluster mode.
+	sentinel *sentinel // Sentinel master discovery, only set in sentinel mode.
+	group    string    // Configuration group.
+	config   Config    // Configuration.
 }
 
 // Redis connection.
@@ -33,13 +42,45 @@ type Config struct {
 	IdleTimeout     time.Duration // Maximum idle time for connection (default is 10 seconds, not allowed to be set to 0)
 	MaxConnLifetime time.Duration // Maximum lifetime of the connection (default is 30 seconds, not allowed to be set to 0)
 	ConnectTimeout  time.Duration // Dial connection timeout.
+	DialTimeout     time.Duration // Alias of ConnectTimeout, used if ConnectTimeout is not set.
+	ReadTimeout     time.Duration // Read timeout of commands (default is 0 means no timeout).
+	WriteTimeout    time.Duration // Write timeout of commands (default is 0 means no timeout).
+	MaxRetries      int           // Maximum number of retries of a command failed with a transient error (default is 0 means no retry).
+	RetryBackoff    time.Duration // Wait time before each retry.
+	PoolSize        int           // Maximum number of connections, it overrides MaxActive if set.
+	MinIdleConns    int           // Number of idle connections dialed when the pool is created.
 	TLS             bool          // Specifies the config to use when a TLS connection is dialed.
 	TLSSkipVerify   bool          // Disables server name verification when connecting over TLS
+	TLSCertFile     string        // Client certificate file for TLS, used along with TLSKeyFile.
+	TLSKeyFile      string        // Client private key file for TLS.
+	TLSCAFile       string        // CA certificate file to verify the server, the system CAs are used if not set.
+	Cluster         bool          // Connects to a redis cluster, commands are routed to the node serving their key.
+	Addrs           []string      // Seed node addresses "host:port" of the cluster (default is Host:Port).
+	MasterName      string        // Name of the master monitored by sentinels, it enables sentinel mode if set.
+	SentinelAddrs   []string      // Sentinel addresses "host:port" used to discover the master.
 }
 
 // Pool statistics.
 type PoolStats struct {
 	redis.PoolStats
+	Hits     uint64 // Number of times an idle connection was reused.
+	Misses   uint64 // Number of times a new connection was dialed.
+	Timeouts uint64 // Number of times waiting for a connection timed out.
+}
+
+// Connection pool counting its statistics.
+type connPool struct {
+	// The counters are accessed atomically, so they are kept 64-bit aligned as first fields.
+	hits     uint64
+	misses   uint64
+	timeouts uint64
+	*redis.Pool
+	dial      func() (redis.Conn, error)            // Dials a new connection.
+	test      func(c redis.Conn, t time.Time) error // Tests an idle connection before reusing it.
+	tlsConfig *tls.Config                           // TLS configuration, nil if TLS is disabled.
+	tlsErr    error                                 // Error of loading the TLS configuration.
+	loadMu    sync.Mutex                            // Protects loads.
+	loads     map[string]*loadCall                  // Running loaders of GetOrLoad by key.
 }
 
 const (
@@ -53,6 +94,10 @@ const (
 var (
 	// Pool map.
 	pools = vmap.NewStrAnyMap(true)
+	// Cluster map.
+	clusters = vmap.NewStrAnyMap(true)
+	// Sentinel map.
+	sentinels = vmap.NewStrAnyMap(true)
 )
 
 // New creates a redis client object with given configuration.
@@ -71,53 +116,182 @@ func New(config Config) *Redis {
 	if config.IdleTimeout == 0 {
 		config.IdleTimeout = gDEFAULT_POOL_IDLE_TIMEOUT
 	}
+	if config.PoolSize > 0 {
+		config.MaxActive = config.PoolSize
+	}
+	if config.MaxIdle < config.MinIdleConns {
+		config.MaxIdle = config.MinIdleConns
+	}
+	if config.ConnectTimeout == 0 {
+		config.ConnectTimeout = config.DialTimeout
+	}
 	if config.ConnectTimeout == 0 {
 		config.ConnectTimeout = gDEFAULT_POOL_CONN_TIMEOUT
 	}
 	if config.MaxConnLifetime == 0 {
 		config.MaxConnLifetime = gDEFAULT_POOL_MAX_LIFE_TIME
 	}
+	if config.Cluster {
+		c := clusters.GetOrSetFuncLock(fmt.Sprintf("%v", config), func() interface{} {
+			return newCluster(config)
+		}).(*cluster)
+		return &Redis{
+			config:  config,
+			cluster: c,
+			pool:    c.getPool(c.seeds[0]),
+		}
+	}
+	if config.MasterName != "" {
+		s := sentinels.GetOrSetFuncLock(fmt.Sprintf("%v", config), func() interface{} {
+			return newSentinel(config)
+		}).(*sentinel)
+		return &Redis{
+			config:   config,
+			sentinel: s,
+			pool:     s.pool,
+		}
+	}
 	return &Redis{
 		config: config,
 		pool: pools.GetOrSetFuncLock(fmt.Sprintf("%v", config), func() interface{} {
-			return &redis.Pool{
-				Wait:            true,
-				IdleTimeout:     config.IdleTimeout,
-				MaxActive:       config.MaxActive,
-				MaxIdle:         config.MaxIdle,
-				MaxConnLifetime: config.MaxConnLifetime,
-				Dial: func() (redis.Conn, error) {
-					c, err := redis.Dial(
-						"tcp",
-						fmt.Sprintf("%s:%d", config.Host, config.Port),
-						redis.DialConnectTimeout(config.ConnectTimeout),
-						redis.DialUseTLS(config.TLS),
-						redis.DialTLSSkipVerify(config.TLSSkipVerify),
-					)
-					if err != nil {
-						return nil, err
-					}
-					// AUTH
-					if len(config.Pass) > 0 {
-						if _, err := c.Do("AUTH", config.Pass); err != nil {
-							return nil, err
-						}
-					}
-					// DB
-					if _, err := c.Do("SELECT", config.Db); err != nil {
-						return nil, err
-					}
-					return c, nil
-				},
-				// After the conn is taken from the connection pool, to test if the connection is available,
-				// If error is returned then it closes the connection object and recreate a new connection.
-				TestOnBorrow: func(c redis.Conn, t time.Time) error {
-					_, err := c.Do("PING")
-					return err
-				},
+			p := createPool(config, fmt.Sprintf("%s:%d", config.Host, config.Port))
+			go p.fillIdle(config.MinIdleConns)
+			return p
+		}).(*connPool),
+	}
+}
+
+// createPool creates a connection pool to the server at <addr> with given configuration.
+func createPool(config Config, addr string) *connPool {
+	p := &connPool{
+		// After the conn is taken from the connection pool, to test if the connection is available,
+		// If error is returned then it closes the connection object and recreate a new connection.
+		test: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+	p.dial = func() (redis.Conn, error) {
+		return p.dialNode(config, addr)
+	}
+	p.tlsConfig, p.tlsErr = newTLSConfig(config)
+	p.Pool = &redis.Pool{
+		Wait:            true,
+		IdleTimeout:     config.IdleTimeout,
+		MaxActive:       config.MaxActive,
+		MaxIdle:         config.MaxIdle,
+		MaxConnLifetime: config.MaxConnLifetime,
+		Dial: func() (redis.Conn, error) {
+			atomic.AddUint64(&p.misses, 1)
+			return p.dial()
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if err := p.test(c, t); err != nil {
+				return err
 			}
-		}).(*redis.Pool),
+			atomic.AddUint64(&p.hits, 1)
+			return nil
+		},
 	}
+	return p
+}
+
+// fillIdle dials <n> connections and puts them back to the pool as idle connections.
+func (p *connPool) fillIdle(n int) {
+	if p.MaxActive > 0 && n > p.MaxActive {
+		n = p.MaxActive
+	}
+	conns := make([]redis.Conn, 0, n)
+	for i := 0; i < n; i++ {
+		c := p.Get()
+		if c.Err() != nil {
+			c.Close()
+			break
+		}
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// getContext gets a connection using <ctx>, it counts the timeouts of waiting for a connection.
+func (p *connPool) getContext(ctx context.Context) (redis.Conn, error) {
+	c, err := p.GetContext(ctx)
+	if err != nil && err == ctx.Err() {
+		atomic.AddUint64(&p.timeouts, 1)
+	}
+	return c, err
+}
+
+// stats returns the statistics of the pool.
+func (p *connPool) stats() *PoolStats {
+	return &PoolStats{
+		PoolStats: p.Stats(),
+		Hits:      atomic.LoadUint64(&p.hits),
+		Misses:    atomic.LoadUint64(&p.misses),
+		Timeouts:  atomic.LoadUint64(&p.timeouts),
+	}
+}
+
+// dialNode dials the server at <addr>, authenticates and selects the configured db.
+func (p *connPool) dialNode(config Config, addr string) (redis.Conn, error) {
+	if p.tlsErr != nil {
+		return nil, p.tlsErr
+	}
+	c, err := redis.Dial(
+		"tcp",
+		addr,
+		redis.DialConnectTimeout(config.ConnectTimeout),
+		redis.DialReadTimeout(config.ReadTimeout),
+		redis.DialWriteTimeout(config.WriteTimeout),
+		redis.DialUseTLS(config.TLS),
+		redis.DialTLSSkipVerify(config.TLSSkipVerify),
+		redis.DialTLSConfig(p.tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// AUTH
+	if len(config.Pass) > 0 {
+		if _, err := c.Do("AUTH", config.Pass); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	// DB
+	if _, err := c.Do("SELECT", config.Db); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newTLSConfig builds the TLS configuration from the TLS options of <config>.
+// It returns nil if TLS is disabled.
+func newTLSConfig(config Config) (*tls.Config, error) {
+	if !config.TLS {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.TLSSkipVerify}
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if config.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate found in TLS CA file " + config.TLSCAFile)
+		}
+	}
+	return tlsConfig, nil
 }
 
 // NewFromStr creates a redis client object with given configuration string.
@@ -138,18 +312,60 @@ func NewFromStr(str string) (*Redis, error) {
 // It is not necessary to call Close manually.
 func (r *Redis) Close() error {
 	if r.group != "" {
-		// If it is an instance object,
-		// it needs to remove it from the instance Map.
-		instances.Remove(r.group)
+		// If it is an instance object, it needs to remove it from the instance Map,
+		// unless it has been replaced by another client, eg: by ReloadInstance.
+		instances.LockFunc(func(m map[string]interface{}) {
+			if m[r.group] == r {
+				delete(m, r.group)
+			}
+		})
+	}
+	r.unsharePool()
+	return r.closePool()
+}
+
+// closePool closes the connection pool of the client.
+func (r *Redis) closePool() error {
+	if r.cluster != nil {
+		return r.cluster.Close()
+	}
+	if r.sentinel != nil {
+		return r.sentinel.Close()
 	}
-	pools.Remove(fmt.Sprintf("%v", r.config))
 	return r.pool.Close()
 }
 
+// unsharePool removes the connection pool of the client from the shared pool map,
+// so the clients created later with the same configuration do not use it.
+// The pool shared by the same configuration is kept if it is another one.
+func (r *Redis) unsharePool() {
+	var (
+		shared *vmap.StrAnyMap
+		pool   interface{}
+	)
+	switch {
+	case r.cluster != nil:
+		shared, pool = clusters, r.cluster
+	case r.sentinel != nil:
+		shared, pool = sentinels, r.sentinel
+	default:
+		shared, pool = pools, r.pool
+	}
+	key := fmt.Sprintf("%v", r.config)
+	shared.LockFunc(func(m map[string]interface{}) {
+		if m[key] == pool {
+			delete(m, key)
+		}
+	})
+}
+
 // Conn returns a raw underlying connection object,
 // which expose more methods to communicate with server.
 // **You should call Close function manually if you do not use this connection any further.**
 func (r *Redis) Conn() *Conn {
+	if r.cluster != nil {
+		return &Conn{r.cluster.Get()}
+	}
 	return &Conn{r.pool.Get()}
 }
 
@@ -188,26 +404,36 @@ func (r *Redis) SetMaxConnLifetime(value time.Duration) {
 	r.pool.MaxConnLifetime = value
 }
 
-// Stats returns pool's statistics.
+// PoolStats returns pool's statistics.
+// In cluster mode, it returns the statistics of the pool of the first seed node.
+func (r *Redis) PoolStats() *PoolStats {
+	return r.pool.stats()
+}
+
+// Alias of PoolStats, see PoolStats.
 func (r *Redis) Stats() *PoolStats {
-	return &PoolStats{r.pool.Stats()}
+	return r.PoolStats()
 }
 
 // Do sends a command to the server and returns the received reply.
 // Do automatically get a connection from pool, and close it when the reply received.
 // It does not really "close" the connection, but drops it back to the connection pool.
 func (r *Redis) Do(commandName string, args ...interface{}) (interface{}, error) {
-	conn := &Conn{r.pool.Get()}
-	defer conn.Close()
-	return conn.Do(commandName, args...)
+	return r.retry(context.Background(), commandName, func() (interface{}, error) {
+		conn := r.Conn()
+		defer conn.Close()
+		return conn.Do(commandName, args...)
+	})
 }
 
 // DoWithTimeout sends a command to the server and returns the received reply.
 // The timeout overrides the read timeout set when dialing the connection.
 func (r *Redis) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
-	conn := &Conn{r.pool.Get()}
-	defer conn.Close()
-	return conn.DoWithTimeout(timeout, commandName, args...)
+	return r.retry(context.Background(), commandName, func() (interface{}, error) {
+		conn := r.Conn()
+		defer conn.Close()
+		return conn.DoWithTimeout(timeout, commandName, args...)
+	})
 }
 
 // DoVar returns value from Do as vvar.Var.
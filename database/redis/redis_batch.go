@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// MGetMap returns the values of <keys> with MGET, the keys that do not exist are not in the result.
+// In cluster mode, it sends one MGET for the keys of each hash slot.
+func (r *Redis) MGetMap(ctx context.Context, keys ...string) (map[string]string, error) {
+	result := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+	groups := [][]string{keys}
+	if r.cluster != nil {
+		groups = groupKeysBySlot(keys)
+	}
+	for _, group := range groups {
+		values, err := redis.Values(r.DoCtx(ctx, "MGET", redis.Args{}.AddFlat(group)...))
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if v != nil && i < len(group) {
+				result[group[i]], _ = redis.String(v, nil)
+			}
+		}
+	}
+	return result, nil
+}
+
+// MSetMap sets all the keys and values of <kv>, which expire after <ttl> if <ttl> is greater than 0.
+// As MSET cannot set expiry, the SET commands are sent in one pipeline.
+// In cluster mode, the keys are set one by one.
+func (r *Redis) MSetMap(ctx context.Context, kv map[string]string, ttl time.Duration) error {
+	if r.cluster != nil {
+		for k, v := range kv {
+			if err := r.SetCtx(ctx, k, v, ttl); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(kv) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := r.Pipeline(func(p Pipeliner) {
+		for k, v := range kv {
+			if ttl > 0 {
+				p.Send("SET", k, v, "PX", int64(ttl/time.Millisecond))
+			} else {
+				p.Send("SET", k, v)
+			}
+		}
+	})
+	return err
+}
+
+// groupKeysBySlot groups <keys> by their hash slots, keeping the order of the keys in each group.
+func groupKeysBySlot(keys []string) [][]string {
+	var (
+		groups [][]string
+		index  = make(map[int]int)
+	)
+	for _, key := range keys {
+		slot := KeySlot(key)
+		i, ok := index[slot]
+		if !ok {
+			i = len(groups)
+			index[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
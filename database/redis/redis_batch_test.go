@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMGetMap(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	s.Set("a", "1")
+	s.Set("c", "3")
+
+	m, err := r.MGetMap(context.Background(), "a", "b", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"a": "1", "c": "3"}; !reflect.DeepEqual(m, want) {
+		t.Errorf("MGetMap = %v, want %v", m, want)
+	}
+	if m, err = r.MGetMap(context.Background()); err != nil || len(m) != 0 {
+		t.Errorf("MGetMap without key = %v, %v", m, err)
+	}
+}
+
+func TestMSetMap(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	ctx := context.Background()
+
+	if err := r.MSetMap(ctx, map[string]string{"a": "1", "b": "2"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if ttl := s.TTL(key); ttl != time.Minute {
+			t.Errorf("TTL of %s = %v, want %v", key, ttl, time.Minute)
+		}
+	}
+	if v, _ := s.Get("b"); v != "2" {
+		t.Errorf("b = %q, want %q", v, "2")
+	}
+
+	if err := r.MSetMap(ctx, map[string]string{"c": "3"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := s.TTL("c"); ttl != 0 {
+		t.Errorf("TTL of c = %v, want no expiry", ttl)
+	}
+}
+
+func TestGroupKeysBySlot(t *testing.T) {
+	groups := groupKeysBySlot([]string{"{a}1", "b", "{a}2"})
+	want := [][]string{{"{a}1", "{a}2"}, {"b"}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groupKeysBySlot = %v, want %v", groups, want)
+	}
+}
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// loadCall is a running loader of GetOrLoad.
+type loadCall struct {
+	done  chan struct{} // Closed when the loader returns or panics.
+	value string
+	err   error
+}
+
+// GetOrLoad returns the value cached at <key>. If <key> does not exist, it calls <loader>,
+// caches its result at <key> for <ttl> and returns it. The concurrent calls missing the same
+// key wait for one call of <loader> and share its result. If the result cannot be cached,
+// it is returned along with the error. A call waiting for another one returns the error of
+// <ctx> if it is done first.
+func (r *Redis) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader func() (string, error)) (string, error) {
+	value, err := redis.String(r.DoCtx(ctx, "GET", key))
+	if err != redis.ErrNil {
+		return value, err
+	}
+	return r.pool.load(ctx, key, func() (string, error) {
+		value, err := loader()
+		if err != nil {
+			return "", err
+		}
+		return value, r.SetCtx(ctx, key, value, ttl)
+	})
+}
+
+// load calls <fn> once for the concurrent calls with the same <key>, and returns its result to all of them.
+// The waiting calls return the error of <ctx> if it is done before <fn> returns.
+func (p *connPool) load(ctx context.Context, key string, fn func() (string, error)) (string, error) {
+	p.loadMu.Lock()
+	if c, ok := p.loads[key]; ok {
+		p.loadMu.Unlock()
+		select {
+		case <-c.done:
+			return c.value, c.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if p.loads == nil {
+		p.loads = make(map[string]*loadCall)
+	}
+	// The error is kept for the waiting calls if <fn> panics.
+	c := &loadCall{done: make(chan struct{}), err: errors.New("loader of GetOrLoad panicked")}
+	p.loads[key] = c
+	p.loadMu.Unlock()
+
+	defer func() {
+		p.loadMu.Lock()
+		delete(p.loads, key)
+		p.loadMu.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn()
+	return c.value, c.err
+}
@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	ctx := context.Background()
+
+	var calls int32
+	loader := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "loaded", nil
+	}
+	// Miss.
+	v, err := r.GetOrLoad(ctx, "key", time.Minute, loader)
+	if err != nil || v != "loaded" {
+		t.Fatalf("GetOrLoad on miss = %q, %v", v, err)
+	}
+	if cached, _ := s.Get("key"); cached != "loaded" {
+		t.Errorf("cached value = %q, want %q", cached, "loaded")
+	}
+	if ttl := s.TTL("key"); ttl != time.Minute {
+		t.Errorf("TTL of key = %v, want %v", ttl, time.Minute)
+	}
+
+	// Hit.
+	s.Set("key", "cached")
+	if v, err = r.GetOrLoad(ctx, "key", time.Minute, loader); err != nil || v != "cached" {
+		t.Errorf("GetOrLoad on hit = %q, %v", v, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+
+	// The result is not cached if the loader fails.
+	failed := errors.New("failed")
+	if _, err = r.GetOrLoad(ctx, "other", time.Minute, func() (string, error) { return "", failed }); err != failed {
+		t.Errorf("GetOrLoad with failing loader returned %v", err)
+	}
+	if s.Exists("other") {
+		t.Error("failed load is cached")
+	}
+}
+
+func TestGetOrLoadConcurrent(t *testing.T) {
+	_, group := newTestConfig(t)
+	r := Instance(group)
+
+	var (
+		calls   int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	loader := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "loaded", nil
+	}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := r.GetOrLoad(context.Background(), "key", time.Minute, loader); err != nil || v != "loaded" {
+				t.Errorf("GetOrLoad = %q, %v", v, err)
+			}
+		}()
+	}
+	// Let all the calls miss the key before the loader returns.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	_, group := newTestConfig(t)
+	r := Instance(group)
+
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		r.GetOrLoad(context.Background(), "key", time.Minute, func() (string, error) {
+			<-release
+			panic("loader failed")
+		})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	waited := make(chan error, 1)
+	go func() {
+		_, err := r.GetOrLoad(context.Background(), "key", time.Minute, func() (string, error) {
+			return "loaded", nil
+		})
+		waited <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	select {
+	case err := <-waited:
+		if err == nil {
+			t.Error("GetOrLoad waiting for a panicking loader returned nil error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetOrLoad blocked after the loader panicked")
+	}
+
+	// The key can be loaded again.
+	if v, err := r.GetOrLoad(context.Background(), "key", time.Minute, func() (string, error) {
+		return "loaded", nil
+	}); err != nil || v != "loaded" {
+		t.Errorf("GetOrLoad after a panic = %q, %v", v, err)
+	}
+}
+
+func TestGetOrLoadWaitCancel(t *testing.T) {
+	_, group := newTestConfig(t)
+	r := Instance(group)
+
+	release := make(chan struct{})
+	defer close(release)
+	go r.GetOrLoad(context.Background(), "key", time.Minute, func() (string, error) {
+		<-release
+		return "loaded", nil
+	})
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := r.GetOrLoad(ctx, "key", time.Minute, nil); err != context.DeadlineExceeded {
+		t.Errorf("GetOrLoad waiting past its deadline = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
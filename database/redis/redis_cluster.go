@@ -0,0 +1,453 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"utils/conv"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	gCLUSTER_SLOT_COUNT    = 16384 // Number of hash slots of a redis cluster.
+	gCLUSTER_MAX_REDIRECTS = 5     // Maximum MOVED/ASK redirections followed for one command.
+)
+
+// cluster routes commands to the nodes of a redis cluster by the hash slot of their key.
+// It keeps one connection pool per node and refreshes the slot mapping on MOVED redirections
+// and connection errors.
+type cluster struct {
+	config Config
+	seeds  []string // Seed node addresses.
+
+	mu    sync.RWMutex
+	slots [gCLUSTER_SLOT_COUNT]string // Address of the master node serving each slot.
+	pools map[string]*connPool        // Connection pool of each node.
+
+	refreshMu  sync.Mutex   // Protects refreshing.
+	refreshing *refreshCall // Running reload of the slot mapping, nil if none.
+}
+
+// refreshCall is a reload of the slot mapping shared by concurrent callers.
+type refreshCall struct {
+	done chan struct{} // Closed when the reload completes.
+	err  error
+}
+
+// newCluster creates a cluster client with given configuration.
+// The slot mapping is loaded lazily, so it does not fail if the cluster is unreachable.
+func newCluster(config Config) *cluster {
+	c := &cluster{
+		config: config,
+		seeds:  config.Addrs,
+		pools:  make(map[string]*connPool),
+	}
+	if len(c.seeds) == 0 {
+		c.seeds = []string{fmt.Sprintf("%s:%d", config.Host, config.Port)}
+	}
+	return c
+}
+
+// getPool returns the connection pool of node <addr>, creating it if necessary.
+func (c *cluster) getPool(addr string) *connPool {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return p
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok = c.pools[addr]; !ok {
+		p = createPool(c.config, addr)
+		c.pools[addr] = p
+		go p.fillIdle(c.config.MinIdleConns)
+	}
+	return p
+}
+
+// nodeAddr returns the address of the node serving <slot>,
+// it loads the slot mapping if the slot is unknown.
+func (c *cluster) nodeAddr(slot int) (string, error) {
+	c.mu.RLock()
+	addr := c.slots[slot]
+	c.mu.RUnlock()
+	if addr != "" {
+		return addr, nil
+	}
+	if err := c.refresh(); err != nil {
+		return "", err
+	}
+	c.mu.RLock()
+	addr = c.slots[slot]
+	c.mu.RUnlock()
+	if addr == "" {
+		return "", fmt.Errorf("no cluster node serves slot %d", slot)
+	}
+	return addr, nil
+}
+
+// anyAddr returns the address of a node for commands without key.
+func (c *cluster) anyAddr() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for addr := range c.pools {
+		return addr
+	}
+	return c.seeds[0]
+}
+
+// masters returns the addresses of the master nodes serving the slots.
+func (c *cluster) masters() ([]string, error) {
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var (
+		addrs []string
+		seen  = make(map[string]bool)
+	)
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// refresh reloads the slot mapping, concurrent calls wait for and share one reload.
+func (c *cluster) refresh() error {
+	c.refreshMu.Lock()
+	if call := c.refreshing; call != nil {
+		c.refreshMu.Unlock()
+		<-call.done
+		return call.err
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	c.refreshing = call
+	c.refreshMu.Unlock()
+
+	defer func() {
+		c.refreshMu.Lock()
+		c.refreshing = nil
+		c.refreshMu.Unlock()
+		close(call.done)
+	}()
+	call.err = c.loadSlots()
+	return call.err
+}
+
+// refreshAsync reloads the slot mapping in background, unless a reload is running.
+func (c *cluster) refreshAsync() {
+	c.refreshMu.Lock()
+	running := c.refreshing != nil
+	c.refreshMu.Unlock()
+	if !running {
+		go c.refresh()
+	}
+}
+
+// loadSlots loads the slot mapping with CLUSTER SLOTS from the known nodes.
+func (c *cluster) loadSlots() error {
+	c.mu.RLock()
+	addrs := append([]string{}, c.seeds...)
+	for addr := range c.pools {
+		addrs = append(addrs, addr)
+	}
+	c.mu.RUnlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn := c.getPool(addr).Get()
+		reply, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err = c.setSlots(addr, reply); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// setSlots updates the slot mapping with the CLUSTER SLOTS reply received from node <addr>,
+// and closes the pools of the nodes which left the cluster.
+// Each reply item is: start slot, end slot, master [host, port, ...], replicas...
+func (c *cluster) setSlots(addr string, reply []interface{}) error {
+	var slots [gCLUSTER_SLOT_COUNT]string
+	for _, item := range reply {
+		values, err := redis.Values(item, nil)
+		if err != nil || len(values) < 3 {
+			return fmt.Errorf("invalid CLUSTER SLOTS reply: %v", item)
+		}
+		start, err1 := redis.Int(values[0], nil)
+		end, err2 := redis.Int(values[1], nil)
+		master, err3 := redis.Values(values[2], nil)
+		if err1 != nil || err2 != nil || err3 != nil || len(master) < 2 ||
+			start < 0 || end >= gCLUSTER_SLOT_COUNT || start > end {
+			return fmt.Errorf("invalid CLUSTER SLOTS reply: %v", item)
+		}
+		host, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+		if host == "" {
+			// An empty host means the node that answered the request.
+			host, _, _ = net.SplitHostPort(addr)
+		}
+		node := net.JoinHostPort(host, strconv.Itoa(port))
+		for i := start; i <= end; i++ {
+			slots[i] = node
+		}
+	}
+	// The seed pools are kept to reload the mapping.
+	nodes := make(map[string]bool)
+	for _, node := range slots {
+		nodes[node] = true
+	}
+	for _, node := range c.seeds {
+		nodes[node] = true
+	}
+	var departed []*connPool
+	c.mu.Lock()
+	c.slots = slots
+	for node, p := range c.pools {
+		if !nodes[node] {
+			departed = append(departed, p)
+			delete(c.pools, node)
+		}
+	}
+	c.mu.Unlock()
+	for _, p := range departed {
+		p.Close()
+	}
+	return nil
+}
+
+// Get returns a connection which routes each command to the node serving its key.
+func (c *cluster) Get() redis.Conn {
+	return &clusterConn{cluster: c}
+}
+
+// Close closes the connection pools of all nodes.
+func (c *cluster) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for addr, p := range c.pools {
+		if e := p.Close(); e != nil && err == nil {
+			err = e
+		}
+		delete(c.pools, addr)
+	}
+	return err
+}
+
+// do sends a command to the node serving <slot>, following MOVED and ASK redirections.
+// On a connection error it reloads the slot mapping, as the node may have failed over or
+// left the cluster, and sends the command once again if it can be retried, see isTransientError.
+func (c *cluster) do(timeout time.Duration, slot int, commandName string, args ...interface{}) (interface{}, error) {
+	var (
+		addr      string
+		err       error
+		asking    bool
+		refreshed bool
+	)
+	if slot < 0 {
+		addr = c.anyAddr()
+	} else if addr, err = c.nodeAddr(slot); err != nil {
+		return nil, err
+	}
+	for i := 0; ; i++ {
+		reply, err := c.doNode(addr, asking, timeout, commandName, args...)
+		redirect, ok := err.(redis.Error)
+		if err != nil && !ok && !refreshed {
+			refreshed = true
+			if !isTransientError(commandName, err) {
+				// The command may have been executed, it is not sent again.
+				c.refreshAsync()
+				return reply, err
+			}
+			if c.refresh() != nil {
+				return reply, err
+			}
+			if slot < 0 {
+				addr = c.anyAddr()
+			} else if addr, err = c.nodeAddr(slot); err != nil {
+				return nil, err
+			}
+			asking = false
+			continue
+		}
+		if !ok || i >= gCLUSTER_MAX_REDIRECTS {
+			return reply, err
+		}
+		// The error is like: MOVED 3999 127.0.0.1:6381
+		fields := strings.Fields(string(redirect))
+		if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+			return reply, err
+		}
+		addr, asking = fields[2], fields[0] == "ASK"
+		if !asking {
+			if movedSlot, e := strconv.Atoi(fields[1]); e == nil && movedSlot >= 0 && movedSlot < gCLUSTER_SLOT_COUNT {
+				c.mu.Lock()
+				c.slots[movedSlot] = addr
+				c.mu.Unlock()
+			}
+			// The slots are probably resharded, reload the whole mapping in background.
+			c.refreshAsync()
+		}
+	}
+}
+
+// doNode sends a command to node <addr>.
+func (c *cluster) doNode(addr string, asking bool, timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	conn := c.getPool(addr).Get()
+	defer conn.Close()
+	if asking {
+		if _, err := conn.Do("ASKING"); err != nil {
+			return nil, err
+		}
+	}
+	if timeout > 0 {
+		return redis.DoWithTimeout(conn, timeout, commandName, args...)
+	}
+	return conn.Do(commandName, args...)
+}
+
+// clusterConn implements redis.Conn for a cluster.
+// Do routes each command to the node serving its key. The commands buffered with Send
+// are all sent to the node serving the key of the first one, so the keys of pipelined
+// commands should share a hash tag, eg: {user1000}.following, {user1000}.followers.
+type clusterConn struct {
+	cluster *cluster
+	bound   redis.Conn // Connection used by Send/Flush/Receive.
+	err     error
+}
+
+func (c *clusterConn) Close() error {
+	if c.bound != nil {
+		return c.bound.Close()
+	}
+	return nil
+}
+
+func (c *clusterConn) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.bound != nil {
+		return c.bound.Err()
+	}
+	return nil
+}
+
+func (c *clusterConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if c.bound != nil {
+		return c.bound.Do(commandName, args...)
+	}
+	return c.cluster.do(0, keySlotOfArgs(commandName, args), commandName, args...)
+}
+
+func (c *clusterConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	if c.bound != nil {
+		return redis.DoWithTimeout(c.bound, timeout, commandName, args...)
+	}
+	return c.cluster.do(timeout, keySlotOfArgs(commandName, args), commandName, args...)
+}
+
+func (c *clusterConn) Send(commandName string, args ...interface{}) error {
+	if c.bound == nil {
+		if err := c.bind(keySlotOfArgs(commandName, args)); err != nil {
+			return err
+		}
+	}
+	return c.bound.Send(commandName, args...)
+}
+
+func (c *clusterConn) Flush() error {
+	if c.bound == nil {
+		return nil
+	}
+	return c.bound.Flush()
+}
+
+func (c *clusterConn) Receive() (interface{}, error) {
+	if c.bound == nil {
+		return nil, errors.New("no command sent on cluster connection")
+	}
+	return c.bound.Receive()
+}
+
+func (c *clusterConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	if c.bound == nil {
+		return nil, errors.New("no command sent on cluster connection")
+	}
+	return redis.ReceiveWithTimeout(c.bound, timeout)
+}
+
+// bind binds the connection to the node serving <slot>.
+func (c *clusterConn) bind(slot int) error {
+	addr := ""
+	if slot < 0 {
+		addr = c.cluster.anyAddr()
+	} else if addr, c.err = c.cluster.nodeAddr(slot); c.err != nil {
+		return c.err
+	}
+	c.bound = c.cluster.getPool(addr).Get()
+	return nil
+}
+
+// keySlotOfArgs returns the hash slot of the key of a command, which is its first argument,
+// or the first key after the script and the number of keys for EVAL and EVALSHA.
+// It returns -1 if the command has no key.
+func keySlotOfArgs(commandName string, args []interface{}) int {
+	switch strings.ToUpper(commandName) {
+	case "EVAL", "EVALSHA":
+		if len(args) < 3 || conv.Int(args[1]) == 0 {
+			return -1
+		}
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return -1
+	}
+	return KeySlot(conv.String(args[0]))
+}
+
+// KeySlot returns the cluster hash slot of <key>.
+// If the key contains a hash tag like "{user1000}.following", only the tag is hashed.
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % gCLUSTER_SLOT_COUNT)
+}
+
+// crc16 implements the CRC16-XMODEM checksum used by redis cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,336 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		slot int
+	}{
+		{"123456789", 0x31C3},
+		{"foo", 12182},
+		{"bar", 5061},
+		{"{user1000}.following", KeySlot("user1000")},
+		{"{user1000}.followers", KeySlot("user1000")},
+	}
+	for _, tt := range tests {
+		if got := KeySlot(tt.key); got != tt.slot {
+			t.Errorf("KeySlot(%q) = %d, want %d", tt.key, got, tt.slot)
+		}
+	}
+	if KeySlot("foo{}{bar}") == KeySlot("bar") {
+		t.Error("an empty hash tag must not be used")
+	}
+}
+
+func TestClusterInstance(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	group := t.Name()
+	SetConfig(Config{Cluster: true, Addrs: []string{s.Addr()}}, group)
+	defer RemoveConfig(group)
+	r := Instance(group)
+	defer r.Close()
+	if r.cluster == nil {
+		t.Fatal("cluster config did not create a cluster client")
+	}
+
+	if _, err := r.Do("SET", "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := r.DoVar("GET", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "bar" {
+		t.Errorf("GET foo = %q, want %q", v.String(), "bar")
+	}
+	if _, err := r.Do("PING"); err != nil {
+		t.Error(err)
+	}
+	if addr, _ := r.cluster.nodeAddr(KeySlot("foo")); addr != s.Addr() {
+		t.Errorf("slot of foo is served by %q, want %q", addr, s.Addr())
+	}
+
+	replies, err := r.Pipeline(func(p Pipeliner) {
+		p.Send("SET", "{tag}.a", "1")
+		p.Send("GET", "{tag}.a")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := redis.String(replies[1], nil); v != "1" {
+		t.Errorf("pipelined GET = %q, want %q", v, "1")
+	}
+
+	var keys []string
+	err = r.ScanKeys(context.Background(), "", 0, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil || len(keys) != 2 {
+		t.Errorf("ScanKeys on cluster = %v, %v", keys, err)
+	}
+}
+
+func TestClusterMoved(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Set("foo", "bar")
+
+	// A node which redirects all keyed commands to the miniredis server.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveMoved(l, s.Addr())
+
+	c := newCluster(Config{Addrs: []string{l.Addr().String()}})
+	defer c.Close()
+	slot := KeySlot("foo")
+	c.slots[slot] = l.Addr().String()
+	v, err := redis.String(c.do(0, slot, "GET", "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "bar" {
+		t.Errorf("GET foo = %q, want %q", v, "bar")
+	}
+	c.mu.RLock()
+	addr := c.slots[slot]
+	c.mu.RUnlock()
+	if addr != s.Addr() {
+		t.Errorf("slot %d is mapped to %q after MOVED, want %q", slot, addr, s.Addr())
+	}
+}
+
+func TestClusterConnError(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Set("foo", "bar")
+
+	// The slot is served by a node which has gone.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gone := l.Addr().String()
+	l.Close()
+
+	c := newCluster(Config{Addrs: []string{s.Addr()}, ConnectTimeout: time.Second})
+	defer c.Close()
+	slot := KeySlot("foo")
+	c.slots[slot] = gone
+	v, err := redis.String(c.do(0, slot, "GET", "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "bar" {
+		t.Errorf("GET foo = %q, want %q", v, "bar")
+	}
+	c.mu.RLock()
+	_, ok := c.pools[gone]
+	c.mu.RUnlock()
+	if ok {
+		t.Error("pool of the node which left the cluster is not closed")
+	}
+}
+
+func TestClusterRefreshOnce(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var requests int32
+	go serveSlots(l, &requests)
+
+	c := newCluster(Config{Addrs: []string{l.Addr().String()}})
+	defer c.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.refresh(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("concurrent refreshes sent %d CLUSTER SLOTS, want 1", n)
+	}
+	if addr, _ := c.nodeAddr(KeySlot("foo")); addr != l.Addr().String() {
+		t.Errorf("slot of foo is served by %q, want %q", addr, l.Addr())
+	}
+}
+
+func TestClusterConnErrorNotRetried(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var incrs int32
+	go serveDropIncr(l, &incrs)
+
+	c := newCluster(Config{Addrs: []string{l.Addr().String()}})
+	defer c.Close()
+	if _, err := c.do(0, KeySlot("counter"), "INCR", "counter"); err == nil {
+		t.Fatal("INCR on a dropped connection returned nil error")
+	}
+	if n := atomic.LoadInt32(&incrs); n != 1 {
+		t.Errorf("INCR sent %d times after the connection dropped, want 1", n)
+	}
+}
+
+// serveDropIncr answers CLUSTER SLOTS with all the slots served by itself, and closes
+// the connection on INCR without replying, counting the INCR commands in <incrs>.
+func serveDropIncr(l net.Listener, incrs *int32) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				args, err := readCommand(r)
+				if err != nil {
+					return
+				}
+				switch strings.ToUpper(args[0]) {
+				case "CLUSTER":
+					fmt.Fprintf(conn, "*1\r\n*3\r\n:0\r\n:%d\r\n*2\r\n$0\r\n\r\n:%d\r\n",
+						gCLUSTER_SLOT_COUNT-1, l.Addr().(*net.TCPAddr).Port)
+				case "INCR":
+					atomic.AddInt32(incrs, 1)
+					return
+				default:
+					fmt.Fprint(conn, "+OK\r\n")
+				}
+			}
+		}()
+	}
+}
+
+// serveSlots answers CLUSTER SLOTS slowly with all the slots served by itself,
+// and counts the requests in <requests>.
+func serveSlots(l net.Listener, requests *int32) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				args, err := readCommand(r)
+				if err != nil {
+					return
+				}
+				switch strings.ToUpper(args[0]) {
+				case "CLUSTER":
+					atomic.AddInt32(requests, 1)
+					time.Sleep(50 * time.Millisecond)
+					fmt.Fprintf(conn, "*1\r\n*3\r\n:0\r\n:%d\r\n*2\r\n$0\r\n\r\n:%d\r\n",
+						gCLUSTER_SLOT_COUNT-1, l.Addr().(*net.TCPAddr).Port)
+				default:
+					fmt.Fprint(conn, "+OK\r\n")
+				}
+			}
+		}()
+	}
+}
+
+// serveMoved answers SELECT and PING, and replies MOVED <addr> to any other command.
+func serveMoved(l net.Listener, addr string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				args, err := readCommand(r)
+				if err != nil {
+					return
+				}
+				switch strings.ToUpper(args[0]) {
+				case "SELECT", "PING":
+					fmt.Fprint(conn, "+OK\r\n")
+				default:
+					fmt.Fprintf(conn, "-MOVED %d %s\r\n", KeySlot(args[1]), addr)
+				}
+			}
+		}()
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestKeySlotOfArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []interface{}
+		slot    int
+	}{
+		{"GET", []interface{}{"foo"}, KeySlot("foo")},
+		{"PING", nil, -1},
+		{"EVAL", []interface{}{"return 1", 0}, -1},
+		{"evalsha", []interface{}{"abc", 1, "foo", "bar"}, KeySlot("foo")},
+	}
+	for _, tt := range tests {
+		if got := keySlotOfArgs(tt.command, tt.args); got != tt.slot {
+			t.Errorf("keySlotOfArgs(%s, %v) = %d, want %d", tt.command, tt.args, got, tt.slot)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	vmap "utils/container/map"
 	"utils/conv"
 	"utils/text/regex"
+	vstr "utils/text/str"
 )
 
 const (
@@ -77,11 +78,11 @@ func RemoveConfig(name ...string) {
 }
 
 // ConfigFromStr parses and returns config from given str.
-// Eg: host:port[,db,pass?maxIdle=x&maxActive=x&idleTimeout=x&maxConnLifetime=x]
+// Eg: host:port[,db,pass?maxIdle=x&maxActive=x&idleTimeout=x&maxConnLifetime=x&poolSize=x&minIdleConns=x&dialTimeout=x&readTimeout=x&writeTimeout=x&tls=x&skipVerify=x]
 func ConfigFromStr(str string) (config Config, err error) {
 	array, _ := regex.MatchString(`([^:]+):*(\d*),{0,1}(\d*),{0,1}(.*)\?(.+)`, str)
 	if len(array) == 6 {
-		parse, _ := str.Parse(array[5])
+		parse, _ := vstr.Parse(array[5])
 		config = Config{
 			Host: array[1],
 			Port: conv.Int(array[2]),
@@ -103,6 +104,21 @@ func ConfigFromStr(str string) (config Config, err error) {
 		if v, ok := parse["maxConnLifetime"]; ok {
 			config.MaxConnLifetime = conv.Duration(v) * time.Second
 		}
+		if v, ok := parse["poolSize"]; ok {
+			config.PoolSize = conv.Int(v)
+		}
+		if v, ok := parse["minIdleConns"]; ok {
+			config.MinIdleConns = conv.Int(v)
+		}
+		if v, ok := parse["dialTimeout"]; ok {
+			config.DialTimeout = conv.Duration(v) * time.Second
+		}
+		if v, ok := parse["readTimeout"]; ok {
+			config.ReadTimeout = conv.Duration(v) * time.Second
+		}
+		if v, ok := parse["writeTimeout"]; ok {
+			config.WriteTimeout = conv.Duration(v) * time.Second
+		}
 		if v, ok := parse["tls"]; ok {
 			config.TLS = conv.Bool(v)
 		}
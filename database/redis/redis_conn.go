@@ -15,6 +15,21 @@ import (
 // It uses json.Marshal for struct/slice/map type values before committing them to redis.
 // The timeout overrides the read timeout set when dialing the connection.
 func (c *Conn) do(timeout time.Duration, commandName string, args ...interface{}) (reply interface{}, err error) {
+	if err = marshalArgs(args); err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		conn, ok := c.Conn.(redis.ConnWithTimeout)
+		if !ok {
+			return vvar.New(nil), errors.New(`current connection does not support "ConnWithTimeout"`)
+		}
+		return conn.DoWithTimeout(timeout, commandName, args...)
+	}
+	return c.Conn.Do(commandName, args...)
+}
+
+// marshalArgs replaces struct/slice/map type values in <args> with their json.Marshal result.
+func marshalArgs(args []interface{}) (err error) {
 	var (
 		reflectValue reflect.Value
 		reflectKind  reflect.Kind
@@ -35,19 +50,12 @@ func (c *Conn) do(timeout time.Duration, commandName string, args ...interface{}
 			// Ignore slice type of: []byte.
 			if _, ok := v.([]byte); !ok {
 				if args[k], err = json.Marshal(v); err != nil {
-					return nil, err
+					return err
 				}
 			}
 		}
 	}
-	if timeout > 0 {
-		conn, ok := c.Conn.(redis.ConnWithTimeout)
-		if !ok {
-			return vvar.New(nil), errors.New(`current connection does not support "ConnWithTimeout"`)
-		}
-		return conn.DoWithTimeout(timeout, commandName, args...)
-	}
-	return c.Conn.Do(commandName, args...)
+	return nil
 }
 
 // Do sends a command to the server and returns the received reply.
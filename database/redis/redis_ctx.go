@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	vvar "utils/container/var"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// DoCtx sends a command to the server and returns the received reply.
+// The deadline of <ctx> is used as the read timeout of the command, and DoCtx returns
+// ctx.Err() as soon as <ctx> is done. The connection of a cancelled command is dropped
+// back to the pool after its reply is received.
+func (r *Redis) DoCtx(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	getConn := r.pool.getContext
+	if r.cluster != nil {
+		getConn = func(ctx context.Context) (redis.Conn, error) {
+			return r.cluster.Get(), nil
+		}
+	}
+	return r.retry(ctx, commandName, func() (interface{}, error) {
+		return doCtx(ctx, getConn, commandName, args...)
+	})
+}
+
+// doCtx sends a command on a connection got by <getConn>, see DoCtx.
+func doCtx(ctx context.Context, getConn func(context.Context) (redis.Conn, error), commandName string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+	}
+	c, err := getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := &Conn{c}
+	type result struct {
+		reply interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer conn.Close()
+		reply, err := conn.DoWithTimeout(timeout, commandName, args...)
+		done <- result{reply, err}
+	}()
+	select {
+	case res := <-done:
+		return res.reply, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// DoVarCtx returns value from DoCtx as vvar.Var.
+func (r *Redis) DoVarCtx(ctx context.Context, commandName string, args ...interface{}) (*vvar.Var, error) {
+	return resultToVar(r.DoCtx(ctx, commandName, args...))
+}
+
+// Ping checks the connectivity to the server, it is usually used by health checks.
+// As Instance returns nil for a group without configuration, Ping on a nil client
+// returns an error instead of panicking, eg: Instance("cache").Ping(ctx).
+func (r *Redis) Ping(ctx context.Context) error {
+	if r == nil {
+		return errors.New("redis client is nil, the configuration of the group may be missing")
+	}
+	_, err := r.DoCtx(ctx, "PING")
+	return err
+}
+
+// Get returns the value of <key>, the value is nil if <key> does not exist.
+func (r *Redis) Get(key string) (*vvar.Var, error) {
+	return r.GetCtx(context.Background(), key)
+}
+
+// GetCtx is like Get with a context, see DoCtx.
+func (r *Redis) GetCtx(ctx context.Context, key string) (*vvar.Var, error) {
+	return r.DoVarCtx(ctx, "GET", key)
+}
+
+// Set sets <key> to <value>, which expires after <ttl> if <ttl> is greater than 0.
+func (r *Redis) Set(key string, value interface{}, ttl time.Duration) error {
+	return r.SetCtx(context.Background(), key, value, ttl)
+}
+
+// SetCtx is like Set with a context, see DoCtx.
+func (r *Redis) SetCtx(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	var err error
+	if ttl > 0 {
+		_, err = r.DoCtx(ctx, "SET", key, value, "PX", int64(ttl/time.Millisecond))
+	} else {
+		_, err = r.DoCtx(ctx, "SET", key, value)
+	}
+	return err
+}
+
+// Del deletes <keys> and returns the number of deleted keys.
+func (r *Redis) Del(keys ...string) (int, error) {
+	return r.DelCtx(context.Background(), keys...)
+}
+
+// DelCtx is like Del with a context, see DoCtx.
+func (r *Redis) DelCtx(ctx context.Context, keys ...string) (int, error) {
+	return redis.Int(r.DoCtx(ctx, "DEL", redis.Args{}.AddFlat(keys)...))
+}
+
+// Exists checks whether <key> exists.
+func (r *Redis) Exists(key string) (bool, error) {
+	return r.ExistsCtx(context.Background(), key)
+}
+
+// ExistsCtx is like Exists with a context, see DoCtx.
+func (r *Redis) ExistsCtx(ctx context.Context, key string) (bool, error) {
+	return redis.Bool(r.DoCtx(ctx, "EXISTS", key))
+}
+
+// Expire sets the timeout of <key>, it returns false if <key> does not exist.
+func (r *Redis) Expire(key string, ttl time.Duration) (bool, error) {
+	return r.ExpireCtx(context.Background(), key, ttl)
+}
+
+// ExpireCtx is like Expire with a context, see DoCtx.
+func (r *Redis) ExpireCtx(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return redis.Bool(r.DoCtx(ctx, "PEXPIRE", key, int64(ttl/time.Millisecond)))
+}
+
+// Incr increments the number stored at <key> by one and returns the new value.
+func (r *Redis) Incr(key string) (int64, error) {
+	return r.IncrCtx(context.Background(), key)
+}
+
+// IncrCtx is like Incr with a context, see DoCtx.
+func (r *Redis) IncrCtx(ctx context.Context, key string) (int64, error) {
+	return redis.Int64(r.DoCtx(ctx, "INCR", key))
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommandsCtx(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+
+	if err := r.Set("a", "1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set("b", "2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := s.TTL("b"); ttl != time.Minute {
+		t.Errorf("TTL of b = %v, want %v", ttl, time.Minute)
+	}
+	if v, err := r.Get("a"); err != nil || v.String() != "1" {
+		t.Errorf("Get(a) = %v, %v", v, err)
+	}
+	if v, err := r.Get("none"); err != nil || !v.IsNil() {
+		t.Errorf("Get(none) = %v, %v", v, err)
+	}
+	if n, err := r.Incr("a"); err != nil || n != 2 {
+		t.Errorf("Incr(a) = %d, %v", n, err)
+	}
+	if ok, err := r.Expire("a", time.Second); err != nil || !ok {
+		t.Errorf("Expire(a) = %v, %v", ok, err)
+	}
+	if ok, err := r.Exists("a"); err != nil || !ok {
+		t.Errorf("Exists(a) = %v, %v", ok, err)
+	}
+	if n, err := r.Del("a", "b", "none"); err != nil || n != 2 {
+		t.Errorf("Del = %d, %v", n, err)
+	}
+	if ok, err := r.Exists("a"); err != nil || ok {
+		t.Errorf("Exists(a) after Del = %v, %v", ok, err)
+	}
+}
+
+func TestDoCtxCancel(t *testing.T) {
+	_, group := newTestConfig(t)
+	r := Instance(group)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.GetCtx(ctx, "a"); err != context.Canceled {
+		t.Errorf("GetCtx with cancelled context returned %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := r.DoCtx(ctx, "BLPOP", "empty", 0); err != context.Canceled {
+		t.Errorf("blocked DoCtx returned %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("blocked DoCtx returned after %v", elapsed)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := r.DoCtx(ctx, "BLPOP", "empty", 0); err == nil {
+		t.Error("blocked DoCtx did not fail on deadline")
+	}
+}
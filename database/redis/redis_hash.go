@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// HSetStruct stores the fields of struct <v> in the hash <key> with HSET.
+// The hash field of a struct field is given by its "redis" tag, or its name if the tag is absent,
+// and a field with tag "-" is ignored. Only fields of string, bool, integer and float kinds are
+// supported, an error is returned for other kinds.
+func (r *Redis) HSetStruct(ctx context.Context, key string, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("HSetStruct expects a struct, got %T", v)
+	}
+	args := redis.Args{key}
+	err := eachHashField(rv, func(name string, field reflect.Value) error {
+		args = append(args, name, field.Interface())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		return nil
+	}
+	_, err = r.DoCtx(ctx, "HSET", args...)
+	return err
+}
+
+// HGetStruct reads the hash <key> with HGETALL into the struct pointed by <dest>,
+// see HSetStruct for the mapping of the fields. The struct fields whose hash field
+// does not exist are left unchanged.
+func (r *Redis) HGetStruct(ctx context.Context, key string, dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("HGetStruct expects a pointer to struct, got %T", dest)
+	}
+	values, err := redis.StringMap(r.DoCtx(ctx, "HGETALL", key))
+	if err != nil {
+		return err
+	}
+	return eachHashField(rv.Elem(), func(name string, field reflect.Value) error {
+		s, ok := values[name]
+		if !ok {
+			return nil
+		}
+		if err := setHashField(field, s); err != nil {
+			return fmt.Errorf(`invalid value "%s" of hash field "%s": %w`, s, name, err)
+		}
+		return nil
+	})
+}
+
+// eachHashField calls <fn> with the hash field name and value of each exported field of struct <rv>.
+func eachHashField(rv reflect.Value, fn func(name string, field reflect.Value) error) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if tag := sf.Tag.Get("redis"); tag != "" {
+			if tag = strings.Split(tag, ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+		}
+		switch sf.Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf(`unsupported kind %s of field "%s"`, sf.Type.Kind(), sf.Name)
+		}
+		if err := fn(name, rv.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setHashField parses <s> into <field> according to its kind.
+func setHashField(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHashStruct(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	ctx := context.Background()
+
+	type user struct {
+		Name    string  `redis:"name"`
+		Age     uint8   `redis:"age"`
+		Score   float64 `redis:"score"`
+		Active  bool
+		Ignored string `redis:"-"`
+		private int
+	}
+	in := user{Name: "john", Age: 18, Score: 9.5, Active: true, Ignored: "x", private: 1}
+	if err := r.HSetStruct(ctx, "user", &in); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := s.HKeys("user"); len(keys) != 4 {
+		t.Errorf("hash fields = %v, want 4 fields", keys)
+	}
+	if v := s.HGet("user", "score"); v != "9.5" {
+		t.Errorf("score = %q, want %q", v, "9.5")
+	}
+
+	var out user
+	if err := r.HGetStruct(ctx, "user", &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Ignored, in.private = "", 0
+	if out != in {
+		t.Errorf("HGetStruct = %+v, want %+v", out, in)
+	}
+
+	s.HSet("user", "age", "300")
+	if err := r.HGetStruct(ctx, "user", &out); err == nil {
+		t.Error("expected an error for an out of range value")
+	}
+
+	type nested struct {
+		User user
+	}
+	if err := r.HSetStruct(ctx, "nested", nested{}); err == nil {
+		t.Error("expected an error for an unsupported field kind")
+	}
+	if err := r.HGetStruct(ctx, "user", out); err == nil {
+		t.Error("expected an error for a non-pointer destination")
+	}
+}
@@ -1,10 +1,17 @@
 package redis
 
-import vmap "utils/container/map"
+import (
+	"sync"
+
+	vmap "utils/container/map"
+	verror "utils/os/error"
+)
 
 var (
 	// Instance map
 	instances = vmap.NewStrAnyMap(true)
+	// Serializes ReloadInstance calls.
+	reloadMu sync.Mutex
 )
 
 // Instance returns an instance of redis client with specified group.
@@ -28,3 +35,64 @@ func Instance(name ...string) *Redis {
 	}
 	return nil
 }
+
+// CloseInstance closes the redis instance with specified group and removes it,
+// so the next Instance call with the same group creates a new client.
+// The <name> param is unnecessary, if <name> is not passed,
+// it closes the redis instance with default configuration group.
+func CloseInstance(name ...string) error {
+	group := DEFAULT_GROUP_NAME
+	if len(name) > 0 && name[0] != "" {
+		group = name[0]
+	}
+	if v := instances.Get(group); v != nil {
+		return v.(*Redis).Close()
+	}
+	return nil
+}
+
+// Instances returns the group names of the redis instances that have been created.
+func Instances() []string {
+	return instances.Keys()
+}
+
+// ReloadInstance rebuilds the redis instance of specified group from its current configuration,
+// eg: after its password is changed. The new client replaces the former one in the instance map
+// only after it is fully created, then the former client is closed.
+// The <name> param is unnecessary, if <name> is empty, it reloads the instance of default group.
+func ReloadInstance(name string) error {
+	group := DEFAULT_GROUP_NAME
+	if name != "" {
+		group = name
+	}
+	config, ok := GetConfig(group)
+	if !ok {
+		return verror.Newf(`redis configuration of group "%s" not found`, group)
+	}
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	var old *Redis
+	if v := instances.Get(group); v != nil {
+		old = v.(*Redis)
+		// The new client must not share the pool of the former one, even if the configuration is unchanged.
+		old.unsharePool()
+	}
+	r := New(config)
+	r.group = group
+	var created *Redis
+	instances.LockFunc(func(m map[string]interface{}) {
+		if v, ok := m[group]; ok && v != old {
+			// Another instance was created by Instance meanwhile.
+			created = v.(*Redis)
+		}
+		m[group] = r
+	})
+	if created != nil && created.pool != r.pool {
+		created.unsharePool()
+		created.closePool()
+	}
+	if old != nil {
+		return old.closePool()
+	}
+	return nil
+}
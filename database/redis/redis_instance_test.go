@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"utils/conv"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestConfig starts a miniredis server and registers its config under the test name.
+func newTestConfig(t *testing.T) (*miniredis.Miniredis, string) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := t.Name()
+	SetConfig(Config{Host: s.Host(), Port: conv.Int(s.Port())}, group)
+	t.Cleanup(func() {
+		CloseInstance(group)
+		RemoveConfig(group)
+		s.Close()
+	})
+	return s, group
+}
+
+func TestCloseInstance(t *testing.T) {
+	_, group := newTestConfig(t)
+	first := Instance(group)
+	if first == nil {
+		t.Fatal("Instance returned nil for a configured group")
+	}
+	if err := CloseInstance(group); err != nil {
+		t.Fatal(err)
+	}
+	second := Instance(group)
+	if second == nil || second == first {
+		t.Fatal("Instance after CloseInstance did not create a new client")
+	}
+	if _, err := second.Do("PING"); err != nil {
+		t.Error(err)
+	}
+	if err := CloseInstance("never created"); err != nil {
+		t.Errorf("CloseInstance of a missing group = %v, want nil", err)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	s, group := newTestConfig(t)
+	other := group + "_other"
+	SetConfig(Config{Host: s.Host(), Port: conv.Int(s.Port())}, other)
+	defer RemoveConfig(other)
+	defer CloseInstance(other)
+
+	Instance(group)
+	Instance(other)
+	names := make(map[string]bool)
+	for _, name := range Instances() {
+		names[name] = true
+	}
+	if !names[group] || !names[other] {
+		t.Errorf("Instances() = %v, want both %q and %q", Instances(), group, other)
+	}
+}
+
+func TestSetConfigInstance(t *testing.T) {
+	first, group := newTestConfig(t)
+	second, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	first.Set("server", "first")
+	second.Set("server", "second")
+
+	v, err := Instance(group).DoVar("GET", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "first" {
+		t.Errorf("GET server = %q, want first", v.String())
+	}
+
+	// Replacing the config invalidates the cached client.
+	SetConfig(Config{Host: second.Host(), Port: conv.Int(second.Port())}, group)
+	if v, err = Instance(group).DoVar("GET", "server"); err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "second" {
+		t.Errorf("GET server after SetConfig = %q, want second", v.String())
+	}
+
+	RemoveConfig(group)
+	if Instance(group) != nil {
+		t.Error("Instance after RemoveConfig returned non-nil")
+	}
+}
+
+func TestPing(t *testing.T) {
+	s, group := newTestConfig(t)
+	if err := Instance(group).Ping(context.Background()); err != nil {
+		t.Errorf("Ping reachable server: %v", err)
+	}
+
+	s.Close()
+	if err := Instance(group).Ping(context.Background()); err == nil {
+		t.Error("Ping closed server returned nil")
+	}
+
+	err := Instance("missing group").Ping(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "configuration") {
+		t.Errorf("Ping nil instance returned %v", err)
+	}
+}
+
+func TestCloseReplacedInstance(t *testing.T) {
+	_, group := newTestConfig(t)
+	old := Instance(group)
+	if err := ReloadInstance(group); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := Instance(group)
+
+	// Closing the replaced client keeps the new instance and its pool.
+	old.Close()
+	if Instance(group) != reloaded {
+		t.Error("Close of a replaced client removed the new instance")
+	}
+	if _, err := reloaded.Do("PING"); err != nil {
+		t.Errorf("new instance after closing the replaced client: %v", err)
+	}
+	if r := New(reloaded.config); r.pool != reloaded.pool {
+		t.Error("Close of a replaced client unshared the pool of the new instance")
+	}
+}
+
+func TestReloadInstance(t *testing.T) {
+	first, group := newTestConfig(t)
+	second, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	first.Set("server", "first")
+	second.Set("server", "second")
+
+	old := Instance(group)
+	if err := ReloadInstance(group); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := Instance(group)
+	if reloaded == old {
+		t.Fatal("ReloadInstance did not replace the instance")
+	}
+	if _, err := old.Do("PING"); err == nil {
+		t.Error("former instance is not closed")
+	}
+	if _, err := reloaded.Do("PING"); err != nil {
+		t.Errorf("reloaded instance with unchanged config: %v", err)
+	}
+
+	// The configuration is changed without invalidating the instance.
+	configs.Set(group, Config{Host: second.Host(), Port: conv.Int(second.Port())})
+	if v, _ := Instance(group).DoVar("GET", "server"); v.String() != "first" {
+		t.Fatalf("GET server before reload = %q, want first", v.String())
+	}
+	if err := ReloadInstance(group); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := Instance(group).DoVar("GET", "server"); v.String() != "second" {
+		t.Errorf("GET server after reload = %q, want second", v.String())
+	}
+
+	if err := ReloadInstance("missing group"); err == nil {
+		t.Error("expected an error for a group without configuration")
+	}
+}
@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"utils/util/json"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// SetJSON stores the JSON encoding of <v> at <key>, which expires after <ttl> if <ttl> is greater than 0.
+func (r *Redis) SetJSON(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return r.SetCtx(ctx, key, b, ttl)
+}
+
+// GetJSON decodes the JSON value stored at <key> into <dest>.
+// It returns false without error if <key> does not exist.
+func (r *Redis) GetJSON(ctx context.Context, key string, dest interface{}) (found bool, err error) {
+	b, err := redis.Bytes(r.DoCtx(ctx, "GET", key))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err = json.Unmarshal(b, dest); err != nil {
+		return true, fmt.Errorf(`decode JSON value of key "%s": %w`, key, err)
+	}
+	return true, nil
+}
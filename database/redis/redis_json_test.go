@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	ctx := context.Background()
+
+	type user struct {
+		Name string   `json:"name"`
+		Age  int      `json:"age"`
+		Tags []string `json:"tags"`
+	}
+	in := user{Name: "john", Age: 18, Tags: []string{"a", "b"}}
+	if err := r.SetJSON(ctx, "user", in, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := s.TTL("user"); ttl != time.Minute {
+		t.Errorf("TTL of user = %v, want %v", ttl, time.Minute)
+	}
+	var out user
+	found, err := r.GetJSON(ctx, "user", &out)
+	if err != nil || !found {
+		t.Fatalf("GetJSON = %v, %v", found, err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("GetJSON = %+v, want %+v", out, in)
+	}
+
+	found, err = r.GetJSON(ctx, "missing", &out)
+	if err != nil || found {
+		t.Errorf("GetJSON of missing key = %v, %v", found, err)
+	}
+
+	s.Set("invalid", "{")
+	found, err = r.GetJSON(ctx, "invalid", &out)
+	if err == nil || !found {
+		t.Errorf("GetJSON of invalid value = %v, %v", found, err)
+	}
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// WatchKeyspace listens to the keyspace events of the configured db whose names match <pattern>,
+// eg: "expired", "set" or "*", and calls <fn> with the event name and the key of each event.
+// It blocks until <ctx> is done, <fn> returns an error or the connection fails, and returns the error.
+//
+// The events are only published if the notify-keyspace-events option of the server enables
+// keyevent notifications, eg: "Ex" for the expired events. WatchKeyspace returns an error if the
+// option is disabled; it cannot check the option if CONFIG is not allowed by the server.
+func (r *Redis) WatchKeyspace(ctx context.Context, pattern string, fn func(event, key string) error) error {
+	conn, err := r.pool.dial()
+	if err != nil {
+		return err
+	}
+	psc := &redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+	if reply, err := redis.Strings(conn.Do("CONFIG", "GET", "notify-keyspace-events")); err == nil {
+		if len(reply) == 2 && !strings.Contains(reply[1], "E") {
+			return errors.New(`keyevent notifications are disabled, enable them with the notify-keyspace-events option, eg: "Ex"`)
+		}
+	}
+	prefix := fmt.Sprintf("__keyevent@%d__:", r.config.Db)
+	if err = psc.PSubscribe(prefix + pattern); err != nil {
+		return err
+	}
+
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	wg.Add(1)
+	defer wg.Wait()
+	defer close(done)
+	// Closing the connection unblocks Receive when the context is cancelled.
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			psc.Close()
+		case <-done:
+		}
+	}()
+	for {
+		// The watcher may stay idle for long, so it waits without the configured read timeout.
+		switch v := psc.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			if err = fn(strings.TrimPrefix(v.Channel, prefix), string(v.Data)); err != nil {
+				return err
+			}
+		case error:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return v
+		}
+	}
+}
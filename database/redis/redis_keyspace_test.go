@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveKeyspace answers CONFIG GET notify-keyspace-events with <flags>,
+// and publishes an expired event of key "session" to each PSUBSCRIBE.
+// The address of the server is set to <config>.
+func serveKeyspace(t *testing.T, flags string, config Config) *Redis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					args, err := readCommand(r)
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(args[0]) {
+					case "CONFIG":
+						fmt.Fprintf(conn, "*2\r\n$22\r\nnotify-keyspace-events\r\n$%d\r\n%s\r\n", len(flags), flags)
+					case "PSUBSCRIBE":
+						pattern, channel := args[1], "__keyevent@0__:expired"
+						fmt.Fprintf(conn, "*3\r\n$10\r\npsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(pattern), pattern)
+						fmt.Fprintf(conn, "*4\r\n$8\r\npmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n$7\r\nsession\r\n",
+							len(pattern), pattern, len(channel), channel)
+					default:
+						fmt.Fprint(conn, "+OK\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	addr := l.Addr().(*net.TCPAddr)
+	config.Host, config.Port = addr.IP.String(), addr.Port
+	r := New(config)
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestWatchKeyspace(t *testing.T) {
+	r := serveKeyspace(t, "Ex", Config{})
+	stop := errors.New("stop")
+	var event, key string
+	err := r.WatchKeyspace(context.Background(), "expired", func(e, k string) error {
+		event, key = e, k
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("WatchKeyspace returned %v", err)
+	}
+	if event != "expired" || key != "session" {
+		t.Errorf("received event %q of key %q", event, key)
+	}
+}
+
+func TestWatchKeyspaceCancel(t *testing.T) {
+	r := serveKeyspace(t, "Ex", Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := r.WatchKeyspace(ctx, "*", func(event, key string) error {
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("WatchKeyspace returned %v after cancel", err)
+	}
+}
+
+func TestWatchKeyspaceIdle(t *testing.T) {
+	r := serveKeyspace(t, "Ex", Config{ReadTimeout: 50 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	err := r.WatchKeyspace(ctx, "*", func(event, key string) error {
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("idle WatchKeyspace returned %v", err)
+	}
+}
+
+func TestWatchKeyspaceDisabled(t *testing.T) {
+	r := serveKeyspace(t, "", Config{})
+	err := r.WatchKeyspace(context.Background(), "*", func(event, key string) error {
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "notify-keyspace-events") {
+		t.Errorf("WatchKeyspace with notifications disabled returned %v", err)
+	}
+}
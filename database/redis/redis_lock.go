@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// unlockScript deletes the lock only if it is still held with the token of the caller.
+var unlockScript = newScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Lock acquires a lock named <key> which expires after <ttl>, using SET key token NX PX ttl.
+// It does not wait: <acquired> is false if the lock is held by someone else.
+// The returned <unlock> releases the lock only if it is still held by this caller,
+// it is safe to be called more than once and is a no-op if the lock is not acquired.
+func (r *Redis) Lock(ctx context.Context, key string, ttl time.Duration) (unlock func() error, acquired bool, err error) {
+	unlock = func() error { return nil }
+	ms := int64(ttl / time.Millisecond)
+	if ms <= 0 {
+		return unlock, false, errors.New("lock ttl must be at least 1 millisecond")
+	}
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return unlock, false, err
+	}
+	token := hex.EncodeToString(b)
+	reply, err := r.DoCtx(ctx, "SET", key, token, "NX", "PX", ms)
+	if err != nil || reply == nil {
+		return unlock, false, err
+	}
+	var once sync.Once
+	unlock = func() (err error) {
+		once.Do(func() {
+			_, err = r.evalCtx(context.Background(), unlockScript, key, token)
+		})
+		return
+	}
+	return unlock, true, nil
+}
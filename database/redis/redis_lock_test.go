@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	ctx := context.Background()
+
+	unlock, acquired, err := r.Lock(ctx, "lock", time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("first Lock = %v, %v", acquired, err)
+	}
+	if ttl := s.TTL("lock"); ttl != time.Second {
+		t.Errorf("TTL of lock = %v, want %v", ttl, time.Second)
+	}
+
+	unlock2, acquired, err := r.Lock(ctx, "lock", time.Second)
+	if err != nil || acquired {
+		t.Fatalf("contended Lock = %v, %v", acquired, err)
+	}
+	if err := unlock2(); err != nil {
+		t.Errorf("unlock of a lock not acquired: %v", err)
+	}
+	if !s.Exists("lock") {
+		t.Fatal("unlock of a lock not acquired released the lock")
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Exists("lock") {
+		t.Fatal("lock still exists after unlock")
+	}
+	if err := unlock(); err != nil {
+		t.Errorf("second unlock: %v", err)
+	}
+}
+
+func TestLockExpiredRelease(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	ctx := context.Background()
+
+	unlockA, acquired, err := r.Lock(ctx, "lock", time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("Lock A = %v, %v", acquired, err)
+	}
+	// The lock of A expires and B acquires it.
+	s.FastForward(2 * time.Second)
+	unlockB, acquired, err := r.Lock(ctx, "lock", time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("Lock B = %v, %v", acquired, err)
+	}
+
+	if err := unlockA(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Exists("lock") {
+		t.Fatal("unlock of A deleted the lock held by B")
+	}
+	if err := unlockB(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Exists("lock") {
+		t.Fatal("lock still exists after unlock of B")
+	}
+}
+
+func TestLockInvalidTTL(t *testing.T) {
+	_, group := newTestConfig(t)
+	if _, _, err := Instance(group).Lock(context.Background(), "lock", time.Microsecond); err == nil {
+		t.Error("expected an error for a ttl less than 1 millisecond")
+	}
+}
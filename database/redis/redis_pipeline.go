@@ -0,0 +1,62 @@
+package redis
+
+import "github.com/gomodule/redigo/redis"
+
+// Pipeliner buffers commands to be sent to the server in one batch.
+type Pipeliner interface {
+	// Send buffers a command, its reply is returned by Pipeline.
+	Send(commandName string, args ...interface{})
+}
+
+// pipeline implements Pipeliner on a single connection.
+type pipeline struct {
+	conn  *Conn
+	count int   // Number of commands sent.
+	err   error // First error occurred when sending.
+}
+
+func (p *pipeline) Send(commandName string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	if p.err = marshalArgs(args); p.err != nil {
+		return
+	}
+	if p.err = p.conn.Send(commandName, args...); p.err == nil {
+		p.count++
+	}
+}
+
+// Pipeline sends all commands buffered by <fn> in one batch and
+// returns their replies in the order the commands were sent.
+// If any command fails, the replies are still returned along with the first error.
+func (r *Redis) Pipeline(fn func(Pipeliner)) ([]interface{}, error) {
+	conn := r.Conn()
+	defer conn.Close()
+	p := &pipeline{conn: conn}
+	fn(p)
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	var (
+		replies  = make([]interface{}, p.count)
+		firstErr error
+	)
+	for i := range replies {
+		reply, err := conn.Receive()
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return nil, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			reply = err
+		}
+		replies[i] = reply
+	}
+	return replies, firstErr
+}
@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"testing"
+
+	"utils/conv"
+)
+
+func TestPipeline(t *testing.T) {
+	_, group := newTestConfig(t)
+	r := Instance(group)
+	replies, err := r.Pipeline(func(p Pipeliner) {
+		p.Send("SET", "a", "1")
+		p.Send("SET", "b", "2")
+		p.Send("GET", "b")
+		p.Send("GET", "a")
+		p.Send("INCR", "a")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"OK", "OK", "2", "1", "2"}
+	if len(replies) != len(want) {
+		t.Fatalf("got %d replies, want %d", len(replies), len(want))
+	}
+	for i, reply := range replies {
+		if s := conv.String(reply); s != want[i] {
+			t.Errorf("reply %d = %q, want %q", i, s, want[i])
+		}
+	}
+
+	replies, err = r.Pipeline(func(p Pipeliner) {
+		p.Send("SET", "c", "x")
+		p.Send("INCR", "c")
+		p.Send("GET", "c")
+	})
+	if err == nil {
+		t.Error("Pipeline with a failing command returned nil error")
+	}
+	if len(replies) != 3 || conv.String(replies[2]) != "x" {
+		t.Errorf("replies = %v", replies)
+	}
+}
@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"utils/conv"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPoolSize(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := New(Config{Host: s.Host(), Port: conv.Int(s.Port()), PoolSize: 2, ReadTimeout: time.Second})
+	defer r.Close()
+
+	// Hold all the connections of the pool.
+	conns := []*Conn{r.Conn(), r.Conn()}
+	for _, c := range conns {
+		if _, err := c.Do("PING"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := r.PoolStats(); stats.ActiveCount != 2 || stats.Misses != 2 {
+		t.Errorf("stats with 2 connections in use: %+v", stats)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := r.DoCtx(ctx, "PING"); err != context.DeadlineExceeded {
+		t.Errorf("DoCtx on exhausted pool returned %v", err)
+	}
+	if stats := r.PoolStats(); stats.Timeouts != 1 {
+		t.Errorf("Timeouts = %d, want 1", stats.Timeouts)
+	}
+
+	for _, c := range conns {
+		c.Close()
+	}
+	if _, err := r.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	stats := r.PoolStats()
+	if stats.ActiveCount != 2 || stats.IdleCount != 2 || stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("stats after reusing a connection: %+v", stats)
+	}
+}
+
+func TestPoolMinIdleConns(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := New(Config{Host: s.Host(), Port: conv.Int(s.Port()), MinIdleConns: 3})
+	defer r.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for r.PoolStats().IdleCount != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("idle connections not dialed: %+v", r.PoolStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	gSUBSCRIBE_MIN_BACKOFF = 100 * time.Millisecond
+	gSUBSCRIBE_MAX_BACKOFF = 5 * time.Second
+)
+
+// Message is a message received from a subscribed channel.
+type Message struct {
+	Channel string
+	Payload string
+}
+
+// Subscribe subscribes to <channels> and delivers the received messages on the returned channel.
+// The subscription is re-established if the connection is lost, and it is closed along with
+// the returned channel when <ctx> is cancelled.
+func (r *Redis) Subscribe(ctx context.Context, channels ...string) (<-chan Message, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("no channel to subscribe")
+	}
+	psc, err := r.subscribe(channels)
+	if err != nil {
+		return nil, err
+	}
+	messages := make(chan Message)
+	go r.receiveMessages(ctx, psc, channels, messages)
+	return messages, nil
+}
+
+// subscribe dials a dedicated connection and waits until all <channels> are subscribed.
+func (r *Redis) subscribe(channels []string) (*redis.PubSubConn, error) {
+	conn, err := r.pool.dial()
+	if err != nil {
+		return nil, err
+	}
+	psc := &redis.PubSubConn{Conn: conn}
+	if err = psc.Subscribe(redis.Args{}.AddFlat(channels)...); err != nil {
+		psc.Close()
+		return nil, err
+	}
+	for subscribed := 0; subscribed < len(channels); {
+		switch v := psc.Receive().(type) {
+		case redis.Subscription:
+			subscribed++
+		case error:
+			psc.Close()
+			return nil, v
+		}
+	}
+	return psc, nil
+}
+
+func (r *Redis) receiveMessages(ctx context.Context, psc *redis.PubSubConn, channels []string, messages chan<- Message) {
+	var (
+		mu   sync.Mutex
+		done = make(chan struct{})
+	)
+	defer close(messages)
+	defer close(done)
+	// Closing the connection unblocks Receive when the context is cancelled.
+	go func() {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			psc.Close()
+			mu.Unlock()
+		case <-done:
+		}
+	}()
+
+	for {
+		// Subscribers may stay idle for long, so they wait without the configured read timeout.
+		switch v := psc.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			select {
+			case messages <- Message{Channel: v.Channel, Payload: string(v.Data)}:
+			case <-ctx.Done():
+				return
+			}
+		case error:
+			psc.Close()
+			newPsc := r.resubscribe(ctx, channels)
+			if newPsc == nil {
+				return
+			}
+			mu.Lock()
+			if ctx.Err() != nil {
+				mu.Unlock()
+				newPsc.Close()
+				return
+			}
+			psc = newPsc
+			mu.Unlock()
+		}
+	}
+}
+
+// resubscribe retries subscribing with exponential backoff until it succeeds or <ctx> is done.
+func (r *Redis) resubscribe(ctx context.Context, channels []string) *redis.PubSubConn {
+	backoff := gSUBSCRIBE_MIN_BACKOFF
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if psc, err := r.subscribe(channels); err == nil {
+			return psc
+		}
+		if backoff *= 2; backoff > gSUBSCRIBE_MAX_BACKOFF {
+			backoff = gSUBSCRIBE_MAX_BACKOFF
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"utils/conv"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSubscribe(t *testing.T) {
+	s, group := newTestConfig(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	messages, err := Instance(group).Subscribe(ctx, "news", "sports")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Publish("sports", "goal")
+	select {
+	case msg := <-messages:
+		if msg.Channel != "sports" || msg.Payload != "goal" {
+			t.Errorf("received %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-messages:
+		if ok {
+			t.Error("received a message after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message channel not closed after cancel")
+	}
+}
+
+func TestSubscribeReconnect(t *testing.T) {
+	s, group := newTestConfig(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, err := Instance(group).Subscribe(ctx, "news")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart the server on the same address.
+	addr := s.Addr()
+	s.Close()
+	if err := s.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Addr() != addr {
+		t.Fatalf("server restarted on %s, want %s", s.Addr(), addr)
+	}
+
+	deadline := time.After(3 * time.Second)
+	for {
+		s.Publish("news", "back")
+		select {
+		case msg := <-messages:
+			if msg.Payload != "back" {
+				t.Errorf("received %+v", msg)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("subscription not re-established")
+		}
+	}
+}
+
+func TestSubscribeIdle(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := New(Config{Host: s.Host(), Port: conv.Int(s.Port()), ReadTimeout: 50 * time.Millisecond})
+	defer r.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, err := r.Subscribe(ctx, "news")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The subscriber must not time out and reconnect while no message is published.
+	connections := s.TotalConnectionCount()
+	time.Sleep(200 * time.Millisecond)
+	if n := s.TotalConnectionCount(); n != connections {
+		t.Errorf("idle subscriber dialed %d new connections", n-connections)
+	}
+	s.Publish("news", "late")
+	select {
+	case msg := <-messages:
+		if msg.Payload != "late" {
+			t.Errorf("received %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// allowScript increments the counter of the current window, and sets the expiry of the counter
+// when the window starts, so the counter is removed when the window ends.
+var allowScript = newScript(1, `
+local n = redis.call("INCR", KEYS[1])
+if n == 1 or redis.call("PTTL", KEYS[1]) == -1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n`)
+
+// Allow reports whether one more event named <key> is allowed by a fixed window rate limit,
+// which allows <limit> events in every <window>. It also returns the number of events still
+// allowed in the current window. The counter is updated atomically by a lua script,
+// so the limit is shared by all the clients of the server.
+func (r *Redis) Allow(ctx context.Context, key string, limit int, window time.Duration) (allowed bool, remaining int, err error) {
+	ms := int64(window / time.Millisecond)
+	if ms <= 0 {
+		return false, 0, errors.New("rate limit window must be at least 1 millisecond")
+	}
+	n, err := redis.Int(r.evalCtx(ctx, allowScript, key, ms))
+	if err != nil {
+		return false, 0, err
+	}
+	if remaining = limit - n; remaining < 0 {
+		remaining = 0
+	}
+	return n <= limit, remaining, nil
+}
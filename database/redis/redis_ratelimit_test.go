@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		allowed, remaining, err := r.Allow(ctx, "api", 3, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !allowed || remaining != 3-i {
+			t.Errorf("call %d = %v, %d, want true, %d", i, allowed, remaining, 3-i)
+		}
+	}
+	allowed, remaining, err := r.Allow(ctx, "api", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed || remaining != 0 {
+		t.Errorf("call over limit = %v, %d, want false, 0", allowed, remaining)
+	}
+	if ttl := s.TTL("api"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL of counter = %v", ttl)
+	}
+
+	// A new window starts after the counter expires.
+	s.FastForward(time.Minute)
+	if allowed, _, err = r.Allow(ctx, "api", 3, time.Minute); err != nil || !allowed {
+		t.Errorf("call in new window = %v, %v", allowed, err)
+	}
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// readOnlyCommands are the commands which are safe to be sent again
+// if the connection fails after they are sent.
+var readOnlyCommands = map[string]bool{
+	"DBSIZE": true, "ECHO": true, "EXISTS": true, "GET": true, "GETRANGE": true,
+	"HEXISTS": true, "HGET": true, "HGETALL": true, "HKEYS": true, "HLEN": true,
+	"HMGET": true, "HVALS": true, "INFO": true, "KEYS": true, "LINDEX": true,
+	"LLEN": true, "LRANGE": true, "MGET": true, "PING": true, "PTTL": true,
+	"SCAN": true, "SCARD": true, "SISMEMBER": true, "SMEMBERS": true, "STRLEN": true,
+	"TTL": true, "TYPE": true, "ZCARD": true, "ZRANGE": true, "ZRANGEBYSCORE": true,
+	"ZRANK": true, "ZSCORE": true,
+}
+
+// retry calls <do> and calls it again on transient errors, at most Config.MaxRetries times,
+// waiting Config.RetryBackoff before each retry.
+//
+// A command is retried on any command if the connection cannot be dialed, as the command is not sent.
+// A network error after the command is sent is retried only for read-only commands: the server may have
+// executed the command already, so retrying commands like INCR or LPUSH could apply them twice.
+func (r *Redis) retry(ctx context.Context, commandName string, do func() (interface{}, error)) (interface{}, error) {
+	reply, err := do()
+	for i := 0; i < r.config.MaxRetries && err != nil && isTransientError(commandName, err); i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(r.config.RetryBackoff):
+		}
+		reply, err = do()
+	}
+	return reply, err
+}
+
+// isTransientError checks whether the command <commandName> failed with <err> can be retried.
+func isTransientError(commandName string, err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	if !readOnlyCommands[strings.ToUpper(commandName)] {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || err == io.EOF || err == io.ErrUnexpectedEOF
+}
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"utils/conv"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRetry(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Set("a", "1")
+	config := Config{Host: s.Host(), Port: conv.Int(s.Port()), ConnectTimeout: time.Second}
+
+	// The server is down for a moment.
+	s.Close()
+	noRetry := New(config)
+	defer noRetry.Close()
+	if _, err := noRetry.Do("GET", "a"); err == nil {
+		t.Fatal("expected an error without retry")
+	}
+
+	config.MaxRetries, config.RetryBackoff = 5, 50*time.Millisecond
+	r := New(config)
+	defer r.Close()
+	time.AfterFunc(80*time.Millisecond, func() { s.Restart() })
+	v, err := r.DoVar("SET", "b", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "OK" {
+		t.Errorf("SET after retries = %q, want OK", v.String())
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Err: errors.New("connection reset")}
+	tests := []struct {
+		command string
+		err     error
+		want    bool
+	}{
+		{"INCR", dialErr, true},
+		{"get", readErr, true},
+		{"GET", io.EOF, true},
+		{"INCR", readErr, false},
+		{"INCR", io.EOF, false},
+		{"GET", errors.New("ERR wrong type"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransientError(tt.command, tt.err); got != tt.want {
+			t.Errorf("isTransientError(%s, %v) = %v, want %v", tt.command, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ScanKeys iterates the keys matching <match> with SCAN and calls <fn> for each key,
+// so the keys are not loaded into memory at once. <count> is the hint of the number
+// of keys returned by each SCAN. All keys are matched if <match> is empty.
+// The iteration stops at the first error returned by <fn> or by the server.
+// A key may be passed to <fn> more than once, as SCAN does not guarantee uniqueness.
+// In cluster mode, the keys of all master nodes are iterated.
+func (r *Redis) ScanKeys(ctx context.Context, match string, count int64, fn func(key string) error) error {
+	if r.cluster == nil {
+		return scanKeys(ctx, r.pool.getContext, match, count, fn)
+	}
+	addrs, err := r.cluster.masters()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err = scanKeys(ctx, r.cluster.getPool(addr).getContext, match, count, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanKeys iterates the keys of the server of the connections got by <getConn>, see ScanKeys.
+func scanKeys(ctx context.Context, getConn func(context.Context) (redis.Conn, error), match string, count int64, fn func(key string) error) error {
+	args := redis.Args{0}
+	if match != "" {
+		args = args.Add("MATCH", match)
+	}
+	if count > 0 {
+		args = args.Add("COUNT", count)
+	}
+	cursor := int64(0)
+	for {
+		args[0] = cursor
+		reply, err := redis.Values(doCtx(ctx, getConn, "SCAN", args...))
+		if err != nil {
+			return err
+		}
+		var keys []string
+		if _, err = redis.Scan(reply, &cursor, &keys); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err = fn(key); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
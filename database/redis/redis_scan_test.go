@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestScanKeys(t *testing.T) {
+	s, group := newTestConfig(t)
+	r := Instance(group)
+	for i := 0; i < 500; i++ {
+		s.Set(fmt.Sprintf("user:%d", i), "1")
+		s.Set(fmt.Sprintf("order:%d", i), "1")
+	}
+
+	seen := make(map[string]bool)
+	err := r.ScanKeys(context.Background(), "user:*", 50, func(key string) error {
+		seen[key] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 500 {
+		t.Errorf("scanned %d keys, want 500", len(seen))
+	}
+	for key := range seen {
+		if key[:5] != "user:" {
+			t.Errorf("scanned key %q does not match", key)
+		}
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = r.ScanKeys(context.Background(), "", 0, func(key string) error {
+		if n++; n == 10 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 10 {
+		t.Errorf("ScanKeys stopped with %v after %d keys", err, n)
+	}
+}
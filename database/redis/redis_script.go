@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// script is a lua script which is sent by its SHA1 digest once the server has cached it.
+type script struct {
+	keyCount int
+	src      string
+	hash     string
+}
+
+// newScript creates a script with <keyCount> keys.
+func newScript(keyCount int, src string) *script {
+	sum := sha1.Sum([]byte(src))
+	return &script{
+		keyCount: keyCount,
+		src:      src,
+		hash:     hex.EncodeToString(sum[:]),
+	}
+}
+
+// evalCtx runs <s> with EVALSHA, and falls back to EVAL if the server does not have the script cached.
+func (r *Redis) evalCtx(ctx context.Context, s *script, keysAndArgs ...interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(keysAndArgs)+2)
+	args = append(args, s.hash, s.keyCount)
+	args = append(args, keysAndArgs...)
+	reply, err := r.DoCtx(ctx, "EVALSHA", args...)
+	if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), "NOSCRIPT") {
+		args[0] = s.src
+		reply, err = r.DoCtx(ctx, "EVAL", args...)
+	}
+	return reply, err
+}
@@ -0,0 +1,204 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// sentinel discovers the master named Config.MasterName from the sentinels, and follows
+// master promotions by watching the "+switch-master" event of the sentinels.
+type sentinel struct {
+	config Config
+	pool   *connPool // Connection pool to the current master.
+	closed chan struct{}
+	once   sync.Once
+
+	mu     sync.RWMutex
+	master string // Address of the current master, empty if unknown.
+}
+
+// masterConn is a connection to the master at addr.
+type masterConn struct {
+	redis.Conn
+	addr string
+}
+
+func (c *masterConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+}
+
+func (c *masterConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+// newSentinel creates a sentinel client with given configuration.
+// The master is resolved when a connection is dialed, so it does not fail if no sentinel is reachable.
+func newSentinel(config Config) *sentinel {
+	s := &sentinel{
+		config: config,
+		closed: make(chan struct{}),
+	}
+	s.pool = createPool(config, "")
+	s.pool.dial = func() (redis.Conn, error) {
+		addr, err := s.masterAddr()
+		if err != nil {
+			return nil, err
+		}
+		c, err := s.pool.dialNode(config, addr)
+		if err != nil {
+			// The master may be down before the sentinels notice it.
+			s.setMaster("")
+			return nil, err
+		}
+		return &masterConn{Conn: c, addr: addr}, nil
+	}
+	// Connections to the former master are dropped after a promotion.
+	s.pool.test = func(c redis.Conn, t time.Time) error {
+		if mc, ok := c.(*masterConn); ok && mc.addr != s.currentMaster() {
+			return fmt.Errorf("master %s is not the current master", mc.addr)
+		}
+		_, err := c.Do("PING")
+		return err
+	}
+	go s.pool.fillIdle(config.MinIdleConns)
+	go s.watch()
+	return s
+}
+
+// currentMaster returns the known address of the master.
+func (s *sentinel) currentMaster() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.master
+}
+
+func (s *sentinel) setMaster(addr string) {
+	s.mu.Lock()
+	s.master = addr
+	s.mu.Unlock()
+}
+
+// masterAddr returns the address of the master, it asks the sentinels if it is unknown.
+func (s *sentinel) masterAddr() (string, error) {
+	if addr := s.currentMaster(); addr != "" {
+		return addr, nil
+	}
+	if len(s.config.SentinelAddrs) == 0 {
+		return "", errors.New("no sentinel address configured")
+	}
+	var lastErr error
+	for _, sentinelAddr := range s.config.SentinelAddrs {
+		addr, err := s.queryMaster(sentinelAddr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		s.setMaster(addr)
+		return addr, nil
+	}
+	return "", lastErr
+}
+
+// queryMaster asks the sentinel at <sentinelAddr> for the address of the master.
+func (s *sentinel) queryMaster(sentinelAddr string) (string, error) {
+	c, err := s.dialSentinel(sentinelAddr)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	reply, err := redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", s.config.MasterName))
+	if err != nil {
+		if err == redis.ErrNil {
+			return "", fmt.Errorf(`sentinel %s does not know master "%s"`, sentinelAddr, s.config.MasterName)
+		}
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("invalid reply of sentinel %s: %v", sentinelAddr, reply)
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+func (s *sentinel) dialSentinel(addr string) (redis.Conn, error) {
+	return redis.Dial(
+		"tcp",
+		addr,
+		redis.DialConnectTimeout(s.config.ConnectTimeout),
+		redis.DialUseTLS(s.config.TLS),
+		redis.DialTLSSkipVerify(s.config.TLSSkipVerify),
+		redis.DialTLSConfig(s.pool.tlsConfig),
+	)
+}
+
+// watch subscribes to the "+switch-master" event of the sentinels until the sentinel client is closed.
+// The event message is like: <master name> <old ip> <old port> <new ip> <new port>
+func (s *sentinel) watch() {
+	backoff := gSUBSCRIBE_MIN_BACKOFF
+	for i := 0; ; i++ {
+		if len(s.config.SentinelAddrs) > 0 {
+			if err := s.watchSentinel(s.config.SentinelAddrs[i%len(s.config.SentinelAddrs)]); err == nil {
+				backoff = gSUBSCRIBE_MIN_BACKOFF
+			}
+		}
+		select {
+		case <-s.closed:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > gSUBSCRIBE_MAX_BACKOFF {
+			backoff = gSUBSCRIBE_MAX_BACKOFF
+		}
+	}
+}
+
+// watchSentinel receives the "+switch-master" events from one sentinel until the connection fails.
+// It returns nil if the subscription succeeded.
+func (s *sentinel) watchSentinel(addr string) error {
+	c, err := s.dialSentinel(addr)
+	if err != nil {
+		return err
+	}
+	psc := &redis.PubSubConn{Conn: c}
+	if err = psc.Subscribe("+switch-master"); err != nil {
+		psc.Close()
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.closed:
+		case <-done:
+		}
+		psc.Close()
+	}()
+	// The master may have changed while no sentinel was watched.
+	if master, err := s.queryMaster(addr); err == nil {
+		s.setMaster(master)
+	}
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			fields := strings.Fields(string(v.Data))
+			if len(fields) == 5 && fields[0] == s.config.MasterName {
+				s.setMaster(net.JoinHostPort(fields[3], fields[4]))
+			}
+		case error:
+			return nil
+		}
+	}
+}
+
+// Close stops watching the sentinels and closes the connection pool.
+func (s *sentinel) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+	return s.pool.Close()
+}
@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// fakeSentinel answers get-master-addr-by-name and publishes +switch-master on failover.
+type fakeSentinel struct {
+	net.Listener
+	mu          sync.Mutex
+	master      string
+	subscribers []net.Conn
+}
+
+func newFakeSentinel(t *testing.T, master string) *fakeSentinel {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSentinel{Listener: l, master: master}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSentinel) serve() {
+	for {
+		conn, err := s.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				args, err := readCommand(r)
+				if err != nil {
+					return
+				}
+				s.mu.Lock()
+				switch strings.ToUpper(args[0]) {
+				case "SENTINEL":
+					host, port, _ := net.SplitHostPort(s.master)
+					fmt.Fprintf(conn, "*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+				case "SUBSCRIBE":
+					fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+					s.subscribers = append(s.subscribers, conn)
+				default:
+					fmt.Fprint(conn, "-ERR unknown command\r\n")
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+}
+
+// failover promotes <master> and notifies the subscribers.
+func (s *fakeSentinel) failover(name, master string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	oldHost, oldPort, _ := net.SplitHostPort(s.master)
+	newHost, newPort, _ := net.SplitHostPort(master)
+	s.master = master
+	msg := strings.Join([]string{name, oldHost, oldPort, newHost, newPort}, " ")
+	for _, conn := range s.subscribers {
+		fmt.Fprintf(conn, "*3\r\n$7\r\nmessage\r\n$14\r\n+switch-master\r\n$%d\r\n%s\r\n", len(msg), msg)
+	}
+}
+
+func TestSentinelFailover(t *testing.T) {
+	var servers [2]*miniredis.Miniredis
+	for i := range servers {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		s.Set("master", fmt.Sprint(i))
+		servers[i] = s
+	}
+	fs := newFakeSentinel(t, servers[0].Addr())
+
+	r := New(Config{MasterName: "mymaster", SentinelAddrs: []string{fs.Addr().String()}})
+	defer r.Close()
+	if r.sentinel == nil {
+		t.Fatal("sentinel config did not create a sentinel client")
+	}
+	v, err := r.DoVar("GET", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "0" {
+		t.Fatalf("GET master = %q, want %q", v.String(), "0")
+	}
+
+	fs.failover("mymaster", servers[1].Addr())
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		v, err = r.DoVar("GET", "master")
+		if err == nil && v.String() == "1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("commands still target the former master: %v, %v", v, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSentinelUnknownMaster(t *testing.T) {
+	r := New(Config{MasterName: "mymaster"})
+	defer r.Close()
+	if _, err := r.Do("PING"); err == nil {
+		t.Error("expected an error without sentinel address")
+	}
+}
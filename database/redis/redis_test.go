@@ -6,12 +6,12 @@ import (
 	"github.com/garyburd/redigo/redis"
 )
 
-func TestMain(t *testing.T) {
+func TestPubSubConn(t *testing.T) {
 	// t.Fatal(err)
 	c, errc := redis.Dial("tcp", "192.168.182.11:6379")
 	if errc != nil {
 		// t.Fatal(errc)
-		// return
+		t.Skip(errc)
 	}
 	defer c.Close()
 
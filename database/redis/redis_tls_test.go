@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"utils/conv"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key into <dir>.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestTLS(t *testing.T) {
+	certFile, keyFile := writeTestCert(t, t.TempDir())
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	r := New(Config{
+		Host:        s.Host(),
+		Port:        conv.Int(s.Port()),
+		TLS:         true,
+		TLSCertFile: certFile,
+		TLSKeyFile:  keyFile,
+		TLSCAFile:   certFile,
+	})
+	defer r.Close()
+	if r.pool.tlsConfig == nil || r.pool.tlsConfig.RootCAs == nil || len(r.pool.tlsConfig.Certificates) != 1 {
+		t.Fatalf("TLS config not loaded: %+v", r.pool.tlsConfig)
+	}
+	if _, err := r.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server certificate is not trusted without the CA file.
+	untrusted := New(Config{Host: s.Host(), Port: conv.Int(s.Port()), TLS: true, ConnectTimeout: time.Second})
+	defer untrusted.Close()
+	if _, err := untrusted.Do("PING"); err == nil {
+		t.Error("expected an error for an untrusted server certificate")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	if c, err := newTLSConfig(Config{}); c != nil || err != nil {
+		t.Errorf("newTLSConfig without TLS = %v, %v", c, err)
+	}
+	if c, err := newTLSConfig(Config{TLS: true, TLSSkipVerify: true}); err != nil || !c.InsecureSkipVerify {
+		t.Errorf("newTLSConfig with TLSSkipVerify = %+v, %v", c, err)
+	}
+	if _, err := newTLSConfig(Config{TLS: true, TLSCAFile: "not-exist.pem"}); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+	r := New(Config{Host: "127.0.0.1", Port: 1, TLS: true, TLSCAFile: "not-exist.pem"})
+	defer r.Close()
+	if _, err := r.Do("PING"); err == nil {
+		t.Error("expected the TLS error on dial")
+	}
+}
@@ -4,7 +4,10 @@ go 1.15
 
 require (
 	github.com/BurntSushi/toml v0.3.1
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/UnderTreeTech/waterdrop v0.2.0
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/clbanning/mxj v1.8.5-0.20200714211355-ff02cfb8ea28
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/fsnotify/fsnotify v1.4.9
@@ -15,6 +18,7 @@ require (
 	github.com/gqcn/structs v1.1.1
 	github.com/jinzhu/gorm v1.9.16
 	github.com/json-iterator/go v1.1.10
+	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
 	golang.org/x/text v0.3.3
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
 	gopkg.in/mgo.v2 v2.0.0-20190816093944-a6b53ec6cb22
@@ -2,28 +2,638 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 
 	"utils/text/str"
 )
 
+// Scanner reads user input from In, printing prompts to Out.
+// A nil In reads from stdin, and a nil Out writes to stdout.
+//
+// In is buffered on the first read, so that no buffered input is lost between reads,
+// thus it should not be changed after that.
+type Scanner struct {
+	In  io.Reader
+	Out io.Writer
+
+	// DecimalComma makes ScanFloat accept comma as the decimal separator, eg: 3,14.
+	DecimalComma bool
+
+	// EnableHistory makes the lines submitted to the prompts, except the passwords, stored in History.
+	// If In is a terminal, the lines are then read in raw mode, in which the up and down arrows
+	// recall the stored lines, but the other line editing of the terminal is not available.
+	// It is disabled by default, so are the package functions.
+	EnableHistory bool
+
+	// History stores the submitted lines if EnableHistory, the most recent last.
+	History []string
+
+	// PromptColor is the SGR parameters of the ANSI escape code coloring the prompts, eg: "1;36"
+	// for bold cyan. The prompts are not colored if Out is not a terminal, eg: a log file.
+	PromptColor string
+
+	reader  *bufio.Reader   // Buffered In.
+	pending chan readResult // Line read in background by a ScanCtx which returned before it, nil if none.
+}
+
+// readResult is a line read from In with its terminator.
+type readResult struct {
+	line string
+	err  error
+}
+
+// ErrNotInteractive is returned by ScanStrict if the input is not a terminal.
+var ErrNotInteractive = errors.New("input is not interactive")
+
+// Default scanner bound to stdio, used by the package functions.
+var defaultScanner = &Scanner{In: os.Stdin, Out: os.Stdout}
+
 // Scan prints <info> to stdout, reads and returns user input, which stops by '\n'.
 func Scan(info ...interface{}) string {
-	fmt.Print(info...)
-	return readline()
+	return defaultScanner.Scan(info...)
 }
 
 // Scanf prints <info> to stdout with <format>, reads and returns user input, which stops by '\n'.
 func Scanf(format string, info ...interface{}) string {
-	fmt.Printf(format, info...)
-	return readline()
+	return defaultScanner.Scanf(format, info...)
+}
+
+// ScanPassword prints <prompt> to stdout, reads and returns user input without echoing it,
+// which stops by '\n'. The echo is restored after reading, even if the reading fails.
+// If stdin is not a terminal, eg: piped, it reads a line without changing the terminal.
+func ScanPassword(prompt string) (string, error) {
+	return defaultScanner.ScanPassword(prompt)
+}
+
+// ScanDefault prints <prompt> to stdout, reads and returns user input, which stops by '\n'.
+// It returns <def> if the user input is empty, eg: the user presses Enter only.
+func ScanDefault(prompt, def string) string {
+	return defaultScanner.ScanDefault(prompt, def)
+}
+
+// ScanInt prints <prompt> to stdout, reads user input and returns it as an integer.
+// It returns an error if the input is not an integer.
+func ScanInt(prompt string) (int, error) {
+	return defaultScanner.ScanInt(prompt)
+}
+
+// MustScanInt prints <prompt> to stdout and reads user input until an integer is entered.
+// It panics if the input ends before an integer is entered.
+func MustScanInt(prompt string) int {
+	return defaultScanner.MustScanInt(prompt)
+}
+
+// ScanFloat prints <prompt> to stdout, reads user input and returns it as a float.
+// It returns an error if the input is not a number.
+func ScanFloat(prompt string) (float64, error) {
+	return defaultScanner.ScanFloat(prompt)
+}
+
+// MustScanFloat prints <prompt> to stdout and reads user input until a number is entered.
+// It panics if the input ends before a number is entered.
+func MustScanFloat(prompt string) float64 {
+	return defaultScanner.MustScanFloat(prompt)
+}
+
+// ScanBool prints <prompt> to stdout and reads user input until it is one of true, false,
+// 1, 0, yes, no, y, n, on, off, case-insensitively. Unlike Confirm, there is no default answer.
+// It returns an error if the input ends before a valid answer is entered.
+func ScanBool(prompt string) (bool, error) {
+	return defaultScanner.ScanBool(prompt)
+}
+
+// ScanCtx prints <prompt> to stdout, reads and returns user input, which stops by '\n'.
+// It returns ctx.Err() if <ctx> is done before a line is entered.
+//
+// Note that the line is read in background, which is not cancelled with <ctx>:
+// the next scan waits for the line being read and returns it.
+func ScanCtx(ctx context.Context, prompt string) (string, error) {
+	return defaultScanner.ScanCtx(ctx, prompt)
+}
+
+// ScanMultiline prints <prompt> to stdout, reads and returns user input until EOF, eg: Ctrl-D.
+// The returned text does not end with newline.
+func ScanMultiline(prompt string) (string, error) {
+	return defaultScanner.ScanMultiline(prompt)
+}
+
+// Confirm prints <prompt> followed by "[y/N]" or "[Y/n]" to stdout, and reads user answer
+// until it is one of y, yes, n, no, case-insensitively. It returns <defaultYes> if the answer
+// is empty or the input ends.
+func Confirm(prompt string, defaultYes bool) bool {
+	return defaultScanner.Confirm(prompt, defaultYes)
+}
+
+// ScanMasked prints <prompt> to stdout, reads and returns user input, which stops by Enter,
+// echoing <mask> for each character typed. Backspace removes the last character.
+// If stdin is not a terminal, eg: piped, it reads a line without echoing anything.
+func ScanMasked(prompt string, mask rune) (string, error) {
+	return defaultScanner.ScanMasked(prompt, mask)
+}
+
+// Select prints <options> numbered from 1 and <prompt> to stdout, and reads user input
+// until a valid option number is entered. It returns the zero-based index of the chosen option.
+// It returns an error if there is no option or the input ends.
+func Select(prompt string, options []string) (index int, err error) {
+	return defaultScanner.Select(prompt, options)
+}
+
+// ScanValidate prints <prompt> to stdout and reads user input until <validate> accepts it,
+// printing the validation error before prompting again. It panics if the input ends before
+// a valid value is entered.
+func ScanValidate(prompt string, validate func(string) error) string {
+	return defaultScanner.ScanValidate(prompt, validate)
+}
+
+// ScanRequired prints <prompt> to stdout and reads user input until it is not empty.
+// It panics if the input ends before a value is entered.
+func ScanRequired(prompt string) string {
+	return defaultScanner.ScanRequired(prompt)
+}
+
+// ScanSlice prints <prompt> to stdout, reads a line of user input and splits it with <sep>,
+// which is comma if empty. The elements are trimmed and the empty ones are dropped.
+func ScanSlice(prompt, sep string) []string {
+	return defaultScanner.ScanSlice(prompt, sep)
+}
+
+// IsInteractive checks whether stdin is a terminal,
+// which is false if stdin is piped, eg: in a pipeline or CI.
+func IsInteractive() bool {
+	return defaultScanner.IsInteractive()
+}
+
+// ScanStrict is like Scan, but returns ErrNotInteractive without reading anything
+// if stdin is not a terminal, so that a tool can fail fast instead of waiting for input.
+func ScanStrict(info ...interface{}) (string, error) {
+	return defaultScanner.ScanStrict(info...)
+}
+
+// IsInteractive checks whether In is a terminal.
+func (s *Scanner) IsInteractive() bool {
+	f, ok := s.input().(*os.File)
+	return ok && isTerminal(f.Fd())
+}
+
+// ScanStrict is like Scan, but returns ErrNotInteractive without reading anything
+// if In is not a terminal.
+func (s *Scanner) ScanStrict(info ...interface{}) (string, error) {
+	if !s.IsInteractive() {
+		return "", ErrNotInteractive
+	}
+	return s.Scan(info...), nil
+}
+
+// ScanAll reads user input until EOF, and returns each line trimmed.
+// The blank lines are skipped if <skipBlank> is given true.
+func ScanAll(skipBlank ...bool) ([]string, error) {
+	return defaultScanner.ScanAll(skipBlank...)
+}
+
+// Scan prints <info> to Out, reads and returns user input, which stops by '\n'.
+func (s *Scanner) Scan(info ...interface{}) string {
+	s.printPrompt(fmt.Sprint(info...))
+	return s.readline()
+}
+
+// Scanf prints <info> to Out with <format>, reads and returns user input, which stops by '\n'.
+func (s *Scanner) Scanf(format string, info ...interface{}) string {
+	s.printPrompt(fmt.Sprintf(format, info...))
+	return s.readline()
+}
+
+// ScanPassword prints <prompt> to Out, reads and returns user input without echoing it,
+// which stops by '\n'. The echo is disabled only if In is a terminal.
+func (s *Scanner) ScanPassword(prompt string) (string, error) {
+	w := s.output()
+	s.printPrompt(prompt)
+	if s.IsInteractive() {
+		restore, err := disableEcho(s.input().(*os.File).Fd())
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+		// The newline typed by the user is not echoed.
+		defer fmt.Fprintln(w)
+	}
+	return s.readlineRaw()
+}
+
+// ScanMasked prints <prompt> to Out, reads and returns user input, which stops by Enter,
+// echoing <mask> for each character typed. The keystrokes are read one by one only if
+// In is a terminal, else it reads a line without echoing anything.
+func (s *Scanner) ScanMasked(prompt string, mask rune) (string, error) {
+	w := s.output()
+	s.printPrompt(prompt)
+	if !s.IsInteractive() {
+		return s.readlineRaw()
+	}
+	restore, err := enableRaw(s.input().(*os.File).Fd())
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	defer fmt.Fprintln(w)
+	var line inputLine
+	for {
+		r, _, err := s.bufReader().ReadRune()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+		if r == keyEOF && len(line) == 0 {
+			return "", io.EOF
+		}
+		echo, done := line.input(r, mask)
+		fmt.Fprint(w, echo)
+		if done {
+			return string(line), nil
+		}
+	}
+}
+
+// ScanDefault prints <prompt> to Out, reads and returns user input, which stops by '\n'.
+// It returns <def> if the user input is empty.
+func (s *Scanner) ScanDefault(prompt, def string) string {
+	s.printPrompt(prompt)
+	if line := s.readline(); line != "" {
+		return line
+	}
+	return def
+}
+
+// ScanInt prints <prompt> to Out, reads user input and returns it as an integer.
+// It returns an error if the input is not an integer.
+func (s *Scanner) ScanInt(prompt string) (int, error) {
+	s.printPrompt(prompt)
+	line, err := s.readlineErr()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil {
+		return 0, fmt.Errorf(`"%s" is not a valid integer`, line)
+	}
+	return n, nil
+}
+
+// MustScanInt prints <prompt> to Out and reads user input until an integer is entered.
+// It panics if the input ends before an integer is entered.
+func (s *Scanner) MustScanInt(prompt string) int {
+	for {
+		n, err := s.ScanInt(prompt)
+		if err == nil {
+			return n
+		}
+		if err == io.EOF {
+			panic("no integer entered before end of input")
+		}
+		fmt.Fprintf(s.output(), "%v, please try again.\n", err)
+	}
+}
+
+// ScanFloat prints <prompt> to Out, reads user input and returns it as a float.
+// It returns an error if the input is not a number.
+func (s *Scanner) ScanFloat(prompt string) (float64, error) {
+	s.printPrompt(prompt)
+	line, err := s.readlineErr()
+	if err != nil {
+		return 0, err
+	}
+	v := line
+	if s.DecimalComma {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf(`"%s" is not a valid number`, line)
+	}
+	return f, nil
+}
+
+// MustScanFloat prints <prompt> to Out and reads user input until a number is entered.
+// It panics if the input ends before a number is entered.
+func (s *Scanner) MustScanFloat(prompt string) float64 {
+	for {
+		f, err := s.ScanFloat(prompt)
+		if err == nil {
+			return f
+		}
+		if err == io.EOF {
+			panic("no number entered before end of input")
+		}
+		fmt.Fprintf(s.output(), "%v, please try again.\n", err)
+	}
+}
+
+// ScanBool prints <prompt> to Out and reads user input until it is one of true, false,
+// 1, 0, yes, no, y, n, on, off, case-insensitively.
+// It returns an error if the input ends before a valid answer is entered.
+func (s *Scanner) ScanBool(prompt string) (bool, error) {
+	for {
+		s.printPrompt(prompt)
+		line, err := s.readlineErr()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(line) {
+		case "true", "1", "yes", "y", "on":
+			return true, nil
+		case "false", "0", "no", "n", "off":
+			return false, nil
+		}
+		fmt.Fprintf(s.output(), "\"%s\" is not a valid boolean, please try again.\n", line)
+	}
+}
+
+// ScanValidate prints <prompt> to Out and reads user input until <validate> accepts it,
+// printing the validation error before prompting again. It panics if the input ends before
+// a valid value is entered.
+func (s *Scanner) ScanValidate(prompt string, validate func(string) error) string {
+	for {
+		s.printPrompt(prompt)
+		line, err := s.readlineErr()
+		if err == io.EOF {
+			panic("no valid value entered before end of input")
+		}
+		if err == nil {
+			if err = validate(line); err == nil {
+				return line
+			}
+		}
+		fmt.Fprintf(s.output(), "%v, please try again.\n", err)
+	}
+}
+
+// ScanRequired prints <prompt> to Out and reads user input until it is not empty.
+// It panics if the input ends before a value is entered.
+func (s *Scanner) ScanRequired(prompt string) string {
+	return s.ScanValidate(prompt, func(v string) error {
+		if v == "" {
+			return errors.New("this field is required")
+		}
+		return nil
+	})
+}
+
+// ScanSlice prints <prompt> to Out, reads a line of user input and splits it with <sep>,
+// which is comma if empty. The elements are trimmed and the empty ones are dropped.
+func (s *Scanner) ScanSlice(prompt, sep string) []string {
+	if sep == "" {
+		sep = ","
+	}
+	s.printPrompt(prompt)
+	return str.SplitAndTrim(s.readline(), sep)
+}
+
+// ScanCtx prints <prompt> to Out, reads and returns user input, which stops by '\n'.
+// It returns ctx.Err() if <ctx> is done before a line is entered.
+// See package function ScanCtx for the background read.
+//
+// The line is read with the line editing of the terminal even if EnableHistory, so that
+// the terminal is not left in raw mode by the background read, and it is added to History
+// only if it is returned.
+func (s *Scanner) ScanCtx(ctx context.Context, prompt string) (string, error) {
+	s.printPrompt(prompt)
+	if s.pending == nil {
+		r := s.bufReader()
+		pending := make(chan readResult, 1)
+		go func() {
+			// The background read must not touch any state other than the reader.
+			line, err := r.ReadString('\n')
+			pending <- readResult{line, err}
+		}()
+		s.pending = pending
+	}
+	select {
+	case res := <-s.pending:
+		s.pending = nil
+		line, err := trimLineEnd(res.line, res.err)
+		if err != nil {
+			return "", err
+		}
+		line = str.Trim(line)
+		s.addHistory(line)
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// ScanMultiline prints <prompt> to Out, reads and returns user input until EOF.
+// The returned text does not end with newline.
+func (s *Scanner) ScanMultiline(prompt string) (string, error) {
+	s.printPrompt(prompt)
+	b, err := ioutil.ReadAll(s.bufReader())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// Confirm prints <prompt> followed by "[y/N]" or "[Y/n]" to Out, and reads user answer
+// until it is one of y, yes, n, no, case-insensitively. It returns <defaultYes> if the answer
+// is empty or the input ends.
+func (s *Scanner) Confirm(prompt string, defaultYes bool) bool {
+	hint := "[y/N]"
+	if defaultYes {
+		hint = "[Y/n]"
+	}
+	for {
+		s.printPrompt(prompt + " " + hint + " ")
+		switch strings.ToLower(s.readline()) {
+		case "":
+			return defaultYes
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Fprintln(s.output(), "Please answer yes or no.")
+	}
+}
+
+// Select prints <options> numbered from 1 and <prompt> to Out, and reads user input
+// until a valid option number is entered. It returns the zero-based index of the chosen option.
+// It returns an error if there is no option or the input ends.
+func (s *Scanner) Select(prompt string, options []string) (index int, err error) {
+	if len(options) == 0 {
+		return 0, errors.New("no option to select")
+	}
+	w := s.output()
+	for i, option := range options {
+		fmt.Fprintf(w, "%d) %s\n", i+1, option)
+	}
+	for {
+		s.printPrompt(prompt)
+		line, err := s.readlineErr()
+		if err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		fmt.Fprintf(w, "Please enter a number between 1 and %d.\n", len(options))
+	}
+}
+
+// Keys handled in raw mode.
+const (
+	keyEOF       = 0x04 // Ctrl-D
+	keyBackspace = 0x08 // Ctrl-H
+	keyEscape    = 0x1b // Starts the sequences of the arrow keys.
+	keyDelete    = 0x7f // Backspace on most terminals
+)
+
+// inputLine is a line typed in raw mode.
+type inputLine []rune
+
+// input applies keystroke <r> to the line, and returns the text to echo,
+// which is <mask> for a character, and whether the line is complete.
+func (l *inputLine) input(r rune, mask rune) (echo string, done bool) {
+	switch {
+	case r == '\r' || r == '\n':
+		return "", true
+	case r == keyBackspace || r == keyDelete:
+		if len(*l) == 0 {
+			return "", false
+		}
+		*l = (*l)[:len(*l)-1]
+		// Moves back, overwrites the mask with a space, and moves back again.
+		return "\b \b", false
+	case r < ' ':
+		// Ignores other control keys.
+		return "", false
+	}
+	*l = append(*l, r)
+	return string(mask), false
+}
+
+// ScanAll reads user input until EOF, and returns each line trimmed.
+// The blank lines are skipped if <skipBlank> is given true.
+// The lines are not added to the history.
+func (s *Scanner) ScanAll(skipBlank ...bool) ([]string, error) {
+	var lines []string
+	for {
+		line, err := s.readlineRaw()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+		if line = str.Trim(line); line != "" || len(skipBlank) == 0 || !skipBlank[0] {
+			lines = append(lines, line)
+		}
+	}
+}
+
+// printPrompt prints <prompt> to Out, colored with PromptColor if Out is a terminal.
+func (s *Scanner) printPrompt(prompt string) {
+	w := s.output()
+	if f, ok := w.(*os.File); ok && isTerminal(f.Fd()) {
+		prompt = colorize(prompt, s.PromptColor)
+	}
+	fmt.Fprint(w, prompt)
+}
+
+// colorize wraps <text> in the ANSI escape codes of <color>, which is SGR parameters.
+func colorize(text, color string) string {
+	if text == "" || color == "" {
+		return text
+	}
+	return "\x1b[" + color + "m" + text + "\x1b[0m"
+}
+
+// input returns In, or stdin if In is nil.
+func (s *Scanner) input() io.Reader {
+	if s.In == nil {
+		return os.Stdin
+	}
+	return s.In
+}
+
+// output returns Out, or stdout if Out is nil.
+func (s *Scanner) output() io.Writer {
+	if s.Out == nil {
+		return os.Stdout
+	}
+	return s.Out
+}
+
+// bufReader returns the buffered reader of In, creating it on the first call.
+// If a line is being read in background, it waits for the line and puts it back to be read first.
+func (s *Scanner) bufReader() *bufio.Reader {
+	if s.pending != nil {
+		res := <-s.pending
+		s.pending = nil
+		var rest io.Reader = s.reader
+		if res.err != nil && res.err != io.EOF {
+			rest = errReader{res.err}
+		}
+		s.reader = bufio.NewReader(io.MultiReader(strings.NewReader(res.line), rest))
+	}
+	if s.reader == nil {
+		if r, ok := s.input().(*bufio.Reader); ok {
+			s.reader = r
+		} else {
+			s.reader = bufio.NewReader(s.input())
+		}
+	}
+	return s.reader
+}
+
+// readline reads a line and returns it trimmed.
+func (s *Scanner) readline() string {
+	line, _ := s.readlineErr()
+	return line
+}
+
+// readlineRaw reads a line and returns it without the line terminator, keeping the spaces.
+// It returns io.EOF only if the input ends without any character.
+func (s *Scanner) readlineRaw() (string, error) {
+	return trimLineEnd(s.bufReader().ReadString('\n'))
+}
+
+// trimLineEnd returns <line> read with <err> without the line terminator.
+// It returns the error only if it is not io.EOF or the line is empty.
+func trimLineEnd(line string, err error) (string, error) {
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// errReader returns err from every read.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
 }
 
-func readline() string {
-	var s string
-	reader := bufio.NewReader(os.Stdin)
-	s, _ = reader.ReadString('\n')
-	s = str.Trim(s)
-	return s
+// readlineErr reads a line, adds it to the history and returns it trimmed.
+// It returns io.EOF only if the input ends without any character.
+func (s *Scanner) readlineErr() (line string, err error) {
+	if s.EnableHistory && s.IsInteractive() {
+		line, err = s.editLine()
+	} else {
+		line, err = s.readlineRaw()
+	}
+	if err != nil {
+		return "", err
+	}
+	line = str.Trim(line)
+	s.addHistory(line)
+	return line, nil
 }
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	gMAX_HISTORY_SIZE = 1000 // Maximum number of lines kept in Scanner.History.
+)
+
+// addHistory adds <line> to the history if EnableHistory, unless it is empty or the same as the last one.
+func (s *Scanner) addHistory(line string) {
+	if !s.EnableHistory || line == "" {
+		return
+	}
+	if n := len(s.History); n > 0 && s.History[n-1] == line {
+		return
+	}
+	s.History = append(s.History, line)
+	if len(s.History) > gMAX_HISTORY_SIZE {
+		s.History = s.History[len(s.History)-gMAX_HISTORY_SIZE:]
+	}
+}
+
+// editLine reads a line from terminal In in raw mode, in which the up and down arrows
+// recall the lines of the history.
+func (s *Scanner) editLine() (string, error) {
+	restore, err := enableRaw(s.input().(*os.File).Fd())
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	var (
+		w = s.output()
+		r = s.bufReader()
+		e = newHistoryEditor(s.History)
+	)
+	defer fmt.Fprintln(w)
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(e.line) > 0 {
+				return string(e.line), nil
+			}
+			return "", err
+		}
+		var (
+			echo string
+			done bool
+		)
+		switch {
+		case c == keyEOF && len(e.line) == 0:
+			return "", io.EOF
+		case c == keyEscape:
+			switch readEscape(r) {
+			case "[A", "OA":
+				echo = e.up()
+			case "[B", "OB":
+				echo = e.down()
+			}
+		default:
+			echo, done = e.line.input(c, c)
+		}
+		fmt.Fprint(w, echo)
+		if done {
+			return string(e.line), nil
+		}
+	}
+}
+
+// readEscape reads the escape sequence following the escape key, eg: "[A" for the up arrow.
+func readEscape(r *bufio.Reader) string {
+	c, _, err := r.ReadRune()
+	if err != nil || (c != '[' && c != 'O') {
+		return ""
+	}
+	seq := []rune{c}
+	// The sequence ends with a character in range '@' to '~', eg: "[A", "[3~".
+	for {
+		if c, _, err = r.ReadRune(); err != nil {
+			return ""
+		}
+		seq = append(seq, c)
+		if c >= '@' && c <= '~' {
+			return string(seq)
+		}
+	}
+}
+
+// historyEditor edits a line in which the lines of the history can be recalled.
+type historyEditor struct {
+	history []string
+	index   int       // Index of the recalled line, len(history) for the new line.
+	draft   string    // New line typed before recalling the history.
+	line    inputLine // Line being edited.
+}
+
+func newHistoryEditor(history []string) *historyEditor {
+	return &historyEditor{
+		history: history,
+		index:   len(history),
+	}
+}
+
+// up recalls the previous line of the history, and returns the text to echo.
+func (e *historyEditor) up() string {
+	if e.index == 0 {
+		return ""
+	}
+	if e.index == len(e.history) {
+		e.draft = string(e.line)
+	}
+	e.index--
+	return e.replace(e.history[e.index])
+}
+
+// down recalls the next line of the history, or the new line after the last one,
+// and returns the text to echo.
+func (e *historyEditor) down() string {
+	if e.index >= len(e.history) {
+		return ""
+	}
+	e.index++
+	if e.index == len(e.history) {
+		return e.replace(e.draft)
+	}
+	return e.replace(e.history[e.index])
+}
+
+// replace replaces the edited line with <line>, and returns the text to echo,
+// which erases the edited line and prints the new one.
+func (e *historyEditor) replace(line string) string {
+	echo := strings.Repeat("\b \b", len(e.line)) + line
+	e.line = inputLine(line)
+	return echo
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScannerHistory(t *testing.T) {
+	s := &Scanner{In: strings.NewReader("first\n\nsecond\nsecond\n"), Out: &bytes.Buffer{}, EnableHistory: true}
+	for i := 0; i < 4; i++ {
+		s.Scan("> ")
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(s.History, want) {
+		t.Errorf("History = %q, want %q", s.History, want)
+	}
+
+	// The history is disabled by default.
+	s = &Scanner{In: strings.NewReader("first\n"), Out: &bytes.Buffer{}}
+	s.Scan("> ")
+	if len(s.History) != 0 {
+		t.Errorf("History = %q without EnableHistory", s.History)
+	}
+
+	s = &Scanner{EnableHistory: true}
+	for i := 0; i < gMAX_HISTORY_SIZE+10; i++ {
+		s.addHistory(strings.Repeat("x", i+1))
+	}
+	if len(s.History) != gMAX_HISTORY_SIZE || s.History[0] != strings.Repeat("x", 11) {
+		t.Errorf("History is not limited to the last %d lines", gMAX_HISTORY_SIZE)
+	}
+}
+
+func TestHistoryEditor(t *testing.T) {
+	e := newHistoryEditor([]string{"one", "two"})
+	for _, r := range "dr" {
+		e.line.input(r, r)
+	}
+	steps := []struct {
+		move func() string
+		line string
+		echo string
+	}{
+		{e.up, "two", "\b \b\b \btwo"},
+		{e.up, "one", "\b \b\b \b\b \bone"},
+		{e.up, "one", ""},
+		{e.down, "two", "\b \b\b \b\b \btwo"},
+		{e.down, "dr", "\b \b\b \b\b \bdr"},
+		{e.down, "dr", ""},
+	}
+	for i, step := range steps {
+		if echo := step.move(); echo != step.echo {
+			t.Errorf("step %d: echo = %q, want %q", i, echo, step.echo)
+		}
+		if string(e.line) != step.line {
+			t.Errorf("step %d: line = %q, want %q", i, string(e.line), step.line)
+		}
+	}
+
+	// Editing a recalled line does not change the history.
+	e.up()
+	e.line.input('!', '!')
+	if string(e.line) != "two!" || e.history[1] != "two" {
+		t.Errorf("line = %q, history = %q", string(e.line), e.history)
+	}
+}
+
+func TestReadEscape(t *testing.T) {
+	tests := []struct {
+		input string
+		seq   string
+	}{
+		{"[A", "[A"},
+		{"OB", "OB"},
+		{"[3~x", "[3~"},
+		{"x", ""},
+		{"[", ""},
+	}
+	for _, tt := range tests {
+		if seq := readEscape(bufio.NewReader(strings.NewReader(tt.input))); seq != tt.seq {
+			t.Errorf("readEscape(%q) = %q, want %q", tt.input, seq, tt.seq)
+		}
+	}
+}
+
+func TestScanCtxHistory(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	s := &Scanner{In: r, Out: &bytes.Buffer{}, EnableHistory: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = s.ScanCtx(ctx, "> "); err != context.DeadlineExceeded {
+		t.Fatalf("ScanCtx without input returned %v", err)
+	}
+	// The line is read by the background read of the cancelled scan,
+	// it is added to the history only when the next scan returns it.
+	w.WriteString("late\n")
+	time.Sleep(20 * time.Millisecond)
+	if len(s.History) != 0 {
+		t.Errorf("History = %q after a cancelled scan", s.History)
+	}
+	if line, err := s.ScanCtx(context.Background(), "> "); err != nil || line != "late" {
+		t.Fatalf("ScanCtx after a cancelled scan = %q, %v", line, err)
+	}
+
+	w.WriteString("next\n")
+	if line, err := s.ScanCtx(context.Background(), "> "); err != nil || line != "next" {
+		t.Fatalf("ScanCtx = %q, %v", line, err)
+	}
+	if want := []string{"late", "next"}; !reflect.DeepEqual(s.History, want) {
+		t.Errorf("History = %q, want %q", s.History, want)
+	}
+}
+
+func TestScanAfterCancelledScanCtx(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	s := &Scanner{In: r, Out: &bytes.Buffer{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = s.ScanCtx(ctx, "> "); err != context.DeadlineExceeded {
+		t.Fatalf("ScanCtx without input returned %v", err)
+	}
+	// Scan waits for the line of the background read instead of reading concurrently.
+	w.WriteString("first\nsecond\n")
+	if line := s.Scan("> "); line != "first" {
+		t.Errorf("Scan after a cancelled ScanCtx = %q, want %q", line, "first")
+	}
+	if line := s.Scan("> "); line != "second" {
+		t.Errorf("Scan = %q, want %q", line, "second")
+	}
+}
@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScannerScan(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader(" first \nsecond\n"), Out: &out}
+	if line := s.Scan("Name: "); line != "first" {
+		t.Errorf("Scan = %q, want %q", line, "first")
+	}
+	if line := s.Scanf("%s %d: ", "Item", 2); line != "second" {
+		t.Errorf("Scanf = %q, want %q", line, "second")
+	}
+	if line := s.Scan("Name: "); line != "" {
+		t.Errorf("Scan at EOF = %q, want empty", line)
+	}
+	if want := "Name: Item 2: Name: "; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestScanPasswordPiped(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("s3cret \r\nnext\n"), Out: &out}
+	password, err := s.ScanPassword("Password: ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password != "s3cret " {
+		t.Errorf("password = %q, want %q", password, "s3cret ")
+	}
+	if out.String() != "Password: " {
+		t.Errorf("output = %q, want the prompt only", out.String())
+	}
+
+	// A pipe is not a terminal, so the password is read as a plain line.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	w.WriteString("piped")
+	w.Close()
+	s = &Scanner{In: r, Out: &out}
+	if password, err = s.ScanPassword(""); err != nil || password != "piped" {
+		t.Errorf("password from pipe = %q, %v", password, err)
+	}
+	if _, err = s.ScanPassword(""); err == nil {
+		t.Error("expected an error on empty input")
+	}
+}
+
+func TestScanDefault(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("\n  value \n"), Out: &out}
+	if line := s.ScanDefault("Name [guest]: ", "guest"); line != "guest" {
+		t.Errorf("empty input = %q, want the default", line)
+	}
+	if line := s.ScanDefault("Name [guest]: ", "guest"); line != "value" {
+		t.Errorf("input = %q, want %q", line, "value")
+	}
+	if line := s.ScanDefault("Name [guest]: ", "guest"); line != "guest" {
+		t.Errorf("input at EOF = %q, want the default", line)
+	}
+	if want := strings.Repeat("Name [guest]: ", 3); out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestScanInt(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader(" 7 \nabc\n"), Out: &out}
+	if n, err := s.ScanInt("Age: "); err != nil || n != 7 {
+		t.Errorf("ScanInt = %d, %v", n, err)
+	}
+	if _, err := s.ScanInt("Age: "); err == nil || !strings.Contains(err.Error(), "abc") {
+		t.Errorf("ScanInt of non-numeric input returned %v", err)
+	}
+}
+
+func TestMustScanInt(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("abc\n42\n"), Out: &out}
+	if n := s.MustScanInt("Age: "); n != 42 {
+		t.Errorf("MustScanInt = %d, want 42", n)
+	}
+	want := "Age: \"abc\" is not a valid integer, please try again.\nAge: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic at end of input")
+		}
+	}()
+	s.MustScanInt("Age: ")
+}
+
+func TestScanCtx(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("value\n"), Out: &out}
+	line, err := s.ScanCtx(context.Background(), "Name: ")
+	if err != nil || line != "value" {
+		t.Errorf("ScanCtx = %q, %v", line, err)
+	}
+
+	// No input is ever written to the pipe.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	s = &Scanner{In: r, Out: &out}
+	if _, err = s.ScanCtx(ctx, "Name: "); err != context.DeadlineExceeded {
+		t.Errorf("ScanCtx without input returned %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ScanCtx returned after %v", elapsed)
+	}
+}
+
+func TestScanMultiline(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("Fix the parser\n\n  It failed on empty input.\n"), Out: &out}
+	text, err := s.ScanMultiline("Message:\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Fix the parser\n\n  It failed on empty input."; text != want {
+		t.Errorf("ScanMultiline = %q, want %q", text, want)
+	}
+	if out.String() != "Message:\n" {
+		t.Errorf("output = %q, want the prompt only", out.String())
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input      string
+		defaultYes bool
+		want       bool
+	}{
+		{"y\n", false, true},
+		{"YES\n", false, true},
+		{"n\n", true, false},
+		{"No\n", true, false},
+		{"\n", true, true},
+		{"\n", false, false},
+		{"", true, true},
+	}
+	for _, tt := range tests {
+		s := &Scanner{In: strings.NewReader(tt.input), Out: &bytes.Buffer{}}
+		if got := s.Confirm("Delete?", tt.defaultYes); got != tt.want {
+			t.Errorf("Confirm(%q, %v) = %v, want %v", tt.input, tt.defaultYes, got, tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("maybe\ny\n"), Out: &out}
+	if !s.Confirm("Delete?", false) {
+		t.Error("Confirm = false after a valid yes")
+	}
+	if want := "Delete? [y/N] Please answer yes or no.\nDelete? [y/N] "; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestScanMaskedPiped(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("s3cret \nnext\n"), Out: &out}
+	password, err := s.ScanMasked("Password: ", '*')
+	if err != nil || password != "s3cret " {
+		t.Errorf("ScanMasked = %q, %v", password, err)
+	}
+	if out.String() != "Password: " {
+		t.Errorf("output = %q, want the prompt only", out.String())
+	}
+}
+
+func TestInputLine(t *testing.T) {
+	var (
+		line inputLine
+		echo string
+		done bool
+	)
+	for _, r := range "ab\x7fc\x7f\x7f\x7fd\x08é\x01" {
+		e, d := line.input(r, '*')
+		echo += e
+		done = done || d
+	}
+	if done {
+		t.Error("line completed before Enter")
+	}
+	if string(line) != "é" {
+		t.Errorf("line = %q, want %q", string(line), "é")
+	}
+	if want := "**\b \b*\b \b\b \b*\b \b*"; echo != want {
+		t.Errorf("echo = %q, want %q", echo, want)
+	}
+	if _, done = line.input('\r', '*'); !done {
+		t.Error("line not completed by Enter")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("4\nred\n2\n"), Out: &out}
+	index, err := s.Select("Color: ", []string{"red", "green", "blue"})
+	if err != nil || index != 1 {
+		t.Errorf("Select = %d, %v, want 1", index, err)
+	}
+	want := "1) red\n2) green\n3) blue\nColor: " +
+		"Please enter a number between 1 and 3.\nColor: " +
+		"Please enter a number between 1 and 3.\nColor: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if _, err = s.Select("Color: ", []string{"red"}); err == nil {
+		t.Error("expected an error at end of input")
+	}
+	if _, err = s.Select("Color: ", nil); err == nil {
+		t.Error("expected an error without option")
+	}
+}
+
+func TestScanStrict(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("value\n"), Out: &out}
+	if s.IsInteractive() {
+		t.Error("IsInteractive = true for a strings.Reader")
+	}
+	if _, err := s.ScanStrict("Name: "); err != ErrNotInteractive {
+		t.Errorf("ScanStrict returned %v, want ErrNotInteractive", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("output = %q, want nothing", out.String())
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if (&Scanner{In: r}).IsInteractive() {
+		t.Error("IsInteractive = true for a pipe")
+	}
+}
+
+func TestScanValidate(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("ab\nhello\n"), Out: &out}
+	validate := func(v string) error {
+		if len(v) < 3 {
+			return errors.New("at least 3 characters are required")
+		}
+		return nil
+	}
+	if v := s.ScanValidate("Name: ", validate); v != "hello" {
+		t.Errorf("ScanValidate = %q, want %q", v, "hello")
+	}
+	want := "Name: at least 3 characters are required, please try again.\nName: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic at end of input")
+		}
+	}()
+	s.ScanValidate("Name: ", validate)
+}
+
+func TestScanSlice(t *testing.T) {
+	s := &Scanner{In: strings.NewReader(" a, b ,,c \nweb1 web2  web3\n\n"), Out: &bytes.Buffer{}}
+	if v := s.ScanSlice("Tags: ", ""); !reflect.DeepEqual(v, []string{"a", "b", "c"}) {
+		t.Errorf("ScanSlice = %q, want [a b c]", v)
+	}
+	if v := s.ScanSlice("Hosts: ", " "); !reflect.DeepEqual(v, []string{"web1", "web2", "web3"}) {
+		t.Errorf("ScanSlice with space = %q, want [web1 web2 web3]", v)
+	}
+	if v := s.ScanSlice("Tags: ", ","); len(v) != 0 {
+		t.Errorf("ScanSlice of empty input = %q, want empty", v)
+	}
+}
+
+func TestScanFloat(t *testing.T) {
+	s := &Scanner{In: strings.NewReader("3.14\n3,14\n"), Out: &bytes.Buffer{}}
+	if f, err := s.ScanFloat("Ratio: "); err != nil || f != 3.14 {
+		t.Errorf("ScanFloat = %v, %v", f, err)
+	}
+	if _, err := s.ScanFloat("Ratio: "); err == nil || !strings.Contains(err.Error(), "3,14") {
+		t.Errorf("ScanFloat of comma input without DecimalComma returned %v", err)
+	}
+
+	s = &Scanner{In: strings.NewReader("3,14\n3.14\n"), Out: &bytes.Buffer{}, DecimalComma: true}
+	for i := 0; i < 2; i++ {
+		if f, err := s.ScanFloat("Ratio: "); err != nil || f != 3.14 {
+			t.Errorf("ScanFloat with DecimalComma = %v, %v", f, err)
+		}
+	}
+}
+
+func TestMustScanFloat(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("abc\n-0.5\n"), Out: &out}
+	if f := s.MustScanFloat("Ratio: "); f != -0.5 {
+		t.Errorf("MustScanFloat = %v, want -0.5", f)
+	}
+	want := "Ratio: \"abc\" is not a valid number, please try again.\nRatio: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic at end of input")
+		}
+	}()
+	s.MustScanFloat("Ratio: ")
+}
+
+func TestScanBool(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"true", true},
+		{"1", true},
+		{"YES", true},
+		{"y", true},
+		{"On", true},
+		{"false", false},
+		{"0", false},
+		{"no", false},
+		{"N", false},
+		{"off", false},
+	}
+	for _, tt := range tests {
+		s := &Scanner{In: strings.NewReader(tt.input + "\n"), Out: &bytes.Buffer{}}
+		if got, err := s.ScanBool("Enabled: "); err != nil || got != tt.want {
+			t.Errorf("ScanBool(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("\nmaybe\non\n"), Out: &out}
+	if v, err := s.ScanBool("Enabled: "); err != nil || !v {
+		t.Errorf("ScanBool = %v, %v, want true", v, err)
+	}
+	want := "Enabled: \"\" is not a valid boolean, please try again.\n" +
+		"Enabled: \"maybe\" is not a valid boolean, please try again.\nEnabled: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if _, err := s.ScanBool("Enabled: "); err == nil {
+		t.Error("expected an error at end of input")
+	}
+}
+
+func TestScanAll(t *testing.T) {
+	input := "web1\n  web2 \n\nweb3"
+	s := &Scanner{In: strings.NewReader(input)}
+	lines, err := s.ScanAll()
+	if want := []string{"web1", "web2", "", "web3"}; err != nil || !reflect.DeepEqual(lines, want) {
+		t.Errorf("ScanAll = %q, %v, want %q", lines, err, want)
+	}
+	if len(s.History) != 0 {
+		t.Errorf("History = %q, want empty", s.History)
+	}
+
+	s = &Scanner{In: strings.NewReader(input)}
+	lines, err = s.ScanAll(true)
+	if want := []string{"web1", "web2", "web3"}; err != nil || !reflect.DeepEqual(lines, want) {
+		t.Errorf("ScanAll skipping blank lines = %q, %v, want %q", lines, err, want)
+	}
+}
+
+func TestPromptColor(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("y\n"), Out: &out, PromptColor: "1;36"}
+	s.Confirm("Delete?", false)
+	if want := "Delete? [y/N] "; out.String() != want {
+		t.Errorf("output = %q, want %q without color codes", out.String(), want)
+	}
+
+	if text := colorize("Name: ", "1;36"); text != "\x1b[1;36mName: \x1b[0m" {
+		t.Errorf("colorize = %q", text)
+	}
+	if text := colorize("Name: ", ""); text != "Name: " {
+		t.Errorf("colorize without color = %q", text)
+	}
+}
+
+func TestScanRequired(t *testing.T) {
+	var out bytes.Buffer
+	s := &Scanner{In: strings.NewReader("\n  \nvalue\n"), Out: &out}
+	if v := s.ScanRequired("Name: "); v != "value" {
+		t.Errorf("ScanRequired = %q, want %q", v, "value")
+	}
+	want := strings.Repeat("Name: this field is required, please try again.\n", 2) + "Name: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package cmd
+
+import "errors"
+
+// isTerminal always returns false, as terminals are not supported on this platform.
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+// disableEcho is not supported on this platform.
+func disableEcho(fd uintptr) (restore func() error, err error) {
+	return nil, errors.New("disabling terminal echo is not supported on this platform")
+}
+
+// enableRaw is not supported on this platform.
+func enableRaw(fd uintptr) (restore func() error, err error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
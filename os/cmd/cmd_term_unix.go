@@ -0,0 +1,67 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package cmd
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal checks whether file descriptor <fd> is a terminal.
+func isTerminal(fd uintptr) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// disableEcho disables the echo of terminal <fd>, and returns the function restoring it.
+func disableEcho(fd uintptr) (restore func() error, err error) {
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	t := *old
+	t.Lflag &^= syscall.ECHO
+	t.Lflag |= syscall.ICANON | syscall.ISIG
+	if err = setTermios(fd, &t); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return setTermios(fd, old)
+	}, nil
+}
+
+// enableRaw disables the echo and the line buffering of terminal <fd>, so that the keystrokes
+// are read one by one, and returns the function restoring it. The signal keys like Ctrl-C still work.
+func enableRaw(fd uintptr) (restore func() error, err error) {
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	t := *old
+	t.Lflag &^= syscall.ECHO | syscall.ICANON
+	t.Lflag |= syscall.ISIG
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+	if err = setTermios(fd, &t); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return setTermios(fd, old)
+	}, nil
+}
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	t := &syscall.Termios{}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return nil, errno
+	}
+	return t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlWriteTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+)
+
+var bitUnits = []string{"bit", "kbit", "Mbit", "Gbit", "Tbit", "Pbit", "Ebit"}
+
+func ParseBits(bitStr string) (int64, error) {
+	number, unit, ok := splitSizeStr(bitStr)
+	if !ok {
+		return 0, fmt.Errorf("invalid bit size %q", bitStr)
+	}
+	unit = strings.ToLower(unit)
+	factor := float64(1)
+	if len(unit) > 1 {
+		if i := strings.IndexByte("kmgtpe", unit[0]); i >= 0 {
+			for ; i >= 0; i-- {
+				factor *= 1000
+			}
+			unit = unit[1:]
+		}
+	}
+	switch unit {
+	case "", "bit", "bits", "bps", "b/s":
+	case "b", "byte", "bytes":
+		factor *= 8
+	default:
+		return 0, fmt.Errorf("unknown bit unit in %q", bitStr)
+	}
+	return int64(number * factor), nil
+}
+
+func FormatBits(bits int64) string {
+	sign, r := "", float64(bits)
+	if r < 0 {
+		sign, r = "-", -r
+	}
+	i := 0
+	for ; i < len(bitUnits)-1 && r >= 1000; i++ {
+		r /= 1000
+	}
+	return fmt.Sprintf("%s%.2f%s", sign, r, bitUnits[i])
+}
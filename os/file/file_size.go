@@ -1,116 +1,326 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 func Size(path string) int64 {
-	s, e := os.Stat(path)
+	return SizeOpt(path, true)
+}
+
+func SizeOpt(path string, followSymlink bool) int64 {
+	stat := os.Lstat
+	if followSymlink {
+		stat = os.Stat
+	}
+	s, e := stat(path)
 	if e != nil {
 		return 0
 	}
 	return s.Size()
 }
 
+func DirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func SizeGlob(pattern string) (size int64, count int, err error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0, 0, err
+	}
+	buffer := bytes.NewBuffer(nil)
+	for _, match := range matches {
+		info, e := os.Stat(match)
+		if e != nil {
+			buffer.WriteString("\n" + e.Error())
+			continue
+		}
+		if info.IsDir() {
+			continue
+		}
+		size += info.Size()
+		count++
+	}
+	if buffer.Len() > 0 {
+		err = fmt.Errorf("cannot stat some files matching \"%s\":%s", pattern, buffer.String())
+	}
+	return
+}
+
 func ReadableSize(path string) string {
 	return FormatSize(Size(path))
 }
 
+func ReadableSizeTrim(path string) string {
+	return FormatSizeTrim(Size(path))
+}
+
+var sizeUnitNames = [][]string{
+	{"", "b", "bytes"},
+	{"k", "kb", "ki", "kib", "kilobyte"},
+	{"m", "mb", "mi", "mib", "mebibyte"},
+	{"g", "gb", "gi", "gib", "gigabyte"},
+	{"t", "tb", "ti", "tib", "terabyte"},
+	{"p", "pb", "pi", "pib", "petabyte"},
+	{"e", "eb", "ei", "eib", "exabyte"},
+	{"z", "zb", "zi", "zib", "zettabyte"},
+	{"y", "yb", "yi", "yib", "yottabyte"},
+	{"bb", "brontobyte"},
+}
+
+func sizeUnitIndex(unit string) (int, bool) {
+	unit = strings.ToLower(unit)
+	for i, names := range sizeUnitNames {
+		for _, name := range names {
+			if name == unit {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func sizeUnitFactor(unit string) (float64, bool) {
+	i, ok := sizeUnitIndex(unit)
+	if !ok {
+		return 0, false
+	}
+	return math.Pow(1024, float64(i)), true
+}
+
+// StrToSize is like ParseSize, but returns 0 if sizeStr has no valid number,
+// eg: "" or "MB", and -1 if its unit is unknown.
 func StrToSize(sizeStr string) int64 {
+	size, err := ParseSize(sizeStr)
+	if err == nil {
+		return size
+	}
+	s := strings.TrimSpace(sizeStr)
 	i := 0
-	for ; i < len(sizeStr); i++ {
-		if sizeStr[i] == '.' || (sizeStr[i] >= '0' && sizeStr[i] <= '9') {
-			continue
-		} else {
-			break
-		}
+	for i < len(s) && isSizeNumberByte(s[i]) {
+		i++
+	}
+	if _, ok := sizeUnitIndex(strings.TrimSpace(s[i:])); ok {
+		return 0
+	}
+	return -1
+}
+
+func ParseSize(sizeStr string) (int64, error) {
+	number, unit, ok := splitSizeStr(sizeStr)
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", sizeStr)
 	}
+	factor, ok := sizeUnitFactor(unit)
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit in %q", sizeStr)
+	}
+	return int64(number * factor), nil
+}
+
+func ParseSizeCompound(sizeStr string) (int64, error) {
 	var (
-		unit      = sizeStr[i:]
-		number, _ = strconv.ParseFloat(sizeStr[:i], 64)
+		s     = strings.TrimSpace(sizeStr)
+		total float64
+		last  = len(sizeUnitNames)
 	)
-	if unit == "" {
-		return int64(number)
-	}
-	switch strings.ToLower(unit) {
-	case "b", "bytes":
-		return int64(number)
-	case "k", "kb", "ki", "kib", "kilobyte":
-		return int64(number * 1024)
-	case "m", "mb", "mi", "mib", "mebibyte":
-		return int64(number * 1024 * 1024)
-	case "g", "gb", "gi", "gib", "gigabyte":
-		return int64(number * 1024 * 1024 * 1024)
-	case "t", "tb", "ti", "tib", "terabyte":
-		return int64(number * 1024 * 1024 * 1024 * 1024)
-	case "p", "pb", "pi", "pib", "petabyte":
-		return int64(number * 1024 * 1024 * 1024 * 1024 * 1024)
-	case "e", "eb", "ei", "eib", "exabyte":
-		return int64(number * 1024 * 1024 * 1024 * 1024 * 1024 * 1024)
-	case "z", "zb", "zi", "zib", "zettabyte":
-		return int64(number * 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024)
-	case "y", "yb", "yi", "yib", "yottabyte":
-		return int64(number * 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024)
-	case "bb", "brontobyte":
-		return int64(number * 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024)
+	if s == "" {
+		return 0, fmt.Errorf("invalid size %q", sizeStr)
 	}
-	return -1
+	for s != "" {
+		i := 0
+		for i < len(s) && isSizeNumberByte(s[i]) {
+			i++
+		}
+		for i < len(s) && !isSizeNumberByte(s[i]) {
+			i++
+		}
+		number, unit, ok := splitSizeStr(s[:i])
+		if !ok {
+			return 0, fmt.Errorf("invalid size %q", sizeStr)
+		}
+		index, ok := sizeUnitIndex(unit)
+		if !ok {
+			return 0, fmt.Errorf("unknown size unit in %q", sizeStr)
+		}
+		if index >= last {
+			return 0, fmt.Errorf("size units must be in descending order in %q", sizeStr)
+		}
+		total += number * math.Pow(1024, float64(index))
+		last = index
+		s = s[i:]
+	}
+	return int64(total), nil
+}
+
+func isSizeNumberByte(c byte) bool {
+	return c == '.' || c == ',' || (c >= '0' && c <= '9')
+}
+
+func splitSizeStr(sizeStr string) (float64, string, bool) {
+	s := strings.ReplaceAll(strings.TrimSpace(sizeStr), ",", "")
+	i := 0
+	for i < len(s) && isSizeNumberByte(s[i]) {
+		i++
+	}
+	number, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return number, strings.TrimSpace(s[i:]), true
+}
+
+type SizeFormatter struct {
+	Base  float64
+	Units []string
+	// Precision is the number of decimal places, a negative value formats
+	// two places and trims trailing zeros.
+	Precision int
+	Space     bool
+}
+
+var (
+	sizeUnits   = []string{"B", "K", "M", "G", "T", "P", "E", "Z", "Y", "BB"}
+	siSizeUnits = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+
+	BinaryFormatter  = SizeFormatter{Base: 1024, Units: sizeUnits, Precision: 2}
+	DecimalFormatter = SizeFormatter{Base: 1000, Units: siSizeUnits, Precision: 2}
+)
+
+func (f SizeFormatter) Format(raw int64) string {
+	return f.format(raw, false, RoundNearest)
+}
+
+func (f SizeFormatter) format(raw int64, fullSuffix bool, mode RoundMode) string {
+	sign, r := "", float64(raw)
+	if r < 0 {
+		sign, r = "-", -r
+	}
+	for i, unit := range f.Units {
+		if r < f.Base || i == len(f.Units)-1 {
+			if fullSuffix && unit != "B" {
+				unit += "B"
+			}
+			if f.Space {
+				unit = " " + unit
+			}
+			return sign + formatSizeNumber(r, f.Precision, mode) + unit
+		}
+		r /= f.Base
+	}
+	return sign + formatSizeNumber(r, f.Precision, mode)
 }
 
 func FormatSize(raw int64) string {
-	var r float64 = float64(raw)
-	var t float64 = 1024
-	var d float64 = 1
-	if r < t {
-		return fmt.Sprintf("%.2fB", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fK", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fM", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fG", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fT", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fP", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fE", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fZ", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fY", r/d)
-	}
-	d *= 1024
-	t *= 1024
-	if r < t {
-		return fmt.Sprintf("%.2fBB", r/d)
-	}
-	return "TooLarge"
+	return BinaryFormatter.Format(raw)
+}
+
+func FormatSizeSI(raw int64) string {
+	return DecimalFormatter.Format(raw)
+}
+
+type FormatSizeStyle struct {
+	Space      bool
+	FullSuffix bool
+}
+
+func FormatSizeTrim(raw int64) string {
+	return FormatSizePrec(raw, -1)
+}
+
+func FormatSizePrec(raw int64, prec int) string {
+	return formatSize(raw, prec, FormatSizeStyle{}, RoundNearest)
+}
+
+func FormatSizeWith(raw int64, style FormatSizeStyle) string {
+	return formatSize(raw, 2, style, RoundNearest)
+}
+
+// FormatSizeUnit formats raw in the given unit, which is written as its
+// canonical name, eg: "kb" is written as "K". It returns "" for an unknown unit.
+func FormatSizeUnit(raw int64, unit string) string {
+	i, ok := sizeUnitIndex(unit)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%.2f%s", float64(raw)/math.Pow(1024, float64(i)), sizeUnits[i])
+}
+
+type RoundMode int
+
+const (
+	RoundNearest RoundMode = iota
+	RoundUp
+	RoundDown
+)
+
+// FormatSizeRound rounds the magnitude of raw in its display unit, so RoundUp
+// never reports a size smaller than it is.
+func FormatSizeRound(raw int64, mode RoundMode) string {
+	return formatSize(raw, 2, FormatSizeStyle{}, mode)
+}
+
+func formatSize(raw int64, prec int, style FormatSizeStyle, mode RoundMode) string {
+	f := BinaryFormatter
+	f.Precision = prec
+	f.Space = style.Space
+	return f.format(raw, style.FullSuffix, mode)
+}
+
+func formatSizeNumber(f float64, prec int, mode RoundMode) string {
+	digits := prec
+	if digits < 0 {
+		digits = 2
+	}
+	if mode != RoundNearest {
+		scale := math.Pow(10, float64(digits))
+		if mode == RoundUp {
+			f = math.Ceil(f*scale) / scale
+		} else {
+			f = math.Floor(f*scale) / scale
+		}
+	}
+	s := strconv.FormatFloat(f, 'f', digits, 64)
+	if prec >= 0 {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func FormatBytesGrouped(raw int64, sep rune) string {
+	digits := strconv.FormatInt(raw, 10)
+	sign := ""
+	if raw < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var builder strings.Builder
+	builder.WriteString(sign)
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			builder.WriteRune(sep)
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
 }
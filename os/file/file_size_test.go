@@ -0,0 +1,415 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	cases := []struct {
+		raw    int64
+		binary string
+		si     string
+	}{
+		{0, "0.00B", "0.00B"},
+		{999, "999.00B", "999.00B"},
+		{1000, "1000.00B", "1.00kB"},
+		{1024, "1.00K", "1.02kB"},
+		{1500000, "1.43M", "1.50MB"},
+		{1073741824, "1.00G", "1.07GB"},
+		{5000000000000, "4.55T", "5.00TB"},
+	}
+	for _, c := range cases {
+		if s := FormatSize(c.raw); s != c.binary {
+			t.Errorf("FormatSize(%d) = %q, want %q", c.raw, s, c.binary)
+		}
+		if s := FormatSizeSI(c.raw); s != c.si {
+			t.Errorf("FormatSizeSI(%d) = %q, want %q", c.raw, s, c.si)
+		}
+	}
+}
+
+func TestFormatSizePrec(t *testing.T) {
+	cases := []struct {
+		raw  int64
+		prec int
+		want string
+	}{
+		{1572864, 0, "2M"},
+		{1572864, 1, "1.5M"},
+		{1572864, 3, "1.500M"},
+		{1500000, 3, "1.431M"},
+		{1572864, -1, "1.5M"},
+		{4194304, -1, "4M"},
+		{1500000, -1, "1.43M"},
+		{0, -1, "0B"},
+	}
+	for _, c := range cases {
+		if s := FormatSizePrec(c.raw, c.prec); s != c.want {
+			t.Errorf("FormatSizePrec(%d, %d) = %q, want %q", c.raw, c.prec, s, c.want)
+		}
+	}
+}
+
+func TestFormatSizeWith(t *testing.T) {
+	cases := []struct {
+		style FormatSizeStyle
+		want  string
+	}{
+		{FormatSizeStyle{}, "1.50M"},
+		{FormatSizeStyle{Space: true}, "1.50 M"},
+		{FormatSizeStyle{FullSuffix: true}, "1.50MB"},
+		{FormatSizeStyle{Space: true, FullSuffix: true}, "1.50 MB"},
+	}
+	for _, c := range cases {
+		if s := FormatSizeWith(1572864, c.style); s != c.want {
+			t.Errorf("FormatSizeWith(1572864, %+v) = %q, want %q", c.style, s, c.want)
+		}
+	}
+	if s := FormatSizeWith(512, FormatSizeStyle{Space: true, FullSuffix: true}); s != "512.00 B" {
+		t.Errorf("FormatSizeWith(512) = %q, want %q", s, "512.00 B")
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]int{
+		"a.txt":         10,
+		"sub/b.txt":     200,
+		"sub/deep/c.go": 3000,
+	}
+	for name, n := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, make([]byte, n), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "sub"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	size, err := DirSize(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 3210 {
+		t.Errorf("DirSize = %d, want 3210", size)
+	}
+	if _, err := DirSize(filepath.Join(root, "missing")); err == nil {
+		t.Error("DirSize of missing path returned nil error")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"1024", 1024},
+		{"10K", 10240},
+		{"1.5 GB", 1610612736},
+		{"1,024 MB", 1073741824},
+		{" 10 mib ", 10485760},
+		{"2\tkb", 2048},
+	}
+	for _, c := range cases {
+		size, err := ParseSize(c.in)
+		if err != nil {
+			t.Errorf("ParseSize(%q) error: %v", c.in, err)
+			continue
+		}
+		if size != c.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", c.in, size, c.want)
+		}
+		if size = StrToSize(c.in); size != c.want {
+			t.Errorf("StrToSize(%q) = %d, want %d", c.in, size, c.want)
+		}
+	}
+	invalid := map[string]int64{
+		"":       0,
+		"MB":     0,
+		"1..5MB": 0,
+		"1.5 XB": -1,
+		"1 2 MB": -1,
+		"abc":    -1,
+	}
+	for in, want := range invalid {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) returned nil error", in)
+		}
+		if size := StrToSize(in); size != want {
+			t.Errorf("StrToSize(%q) = %d, want %d", in, size, want)
+		}
+	}
+}
+
+func TestFormatSizeNegative(t *testing.T) {
+	cases := map[int64]string{
+		0:        "0.00B",
+		-1:       "-1.00B",
+		-1024:    "-1.00K",
+		-1048576: "-1.00M",
+		-1 << 63: "-8.00E",
+	}
+	for raw, want := range cases {
+		if s := FormatSize(raw); s != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", raw, s, want)
+		}
+	}
+}
+
+func TestFormatSizeUnit(t *testing.T) {
+	const raw = 2147483648
+	cases := map[string]string{
+		"B":   "2147483648.00B",
+		"K":   "2097152.00K",
+		"M":   "2048.00M",
+		"G":   "2.00G",
+		"gb":  "2.00G",
+		"kib": "2097152.00K",
+		"":    "2147483648.00B",
+		"X":   "",
+	}
+	for unit, want := range cases {
+		if s := FormatSizeUnit(raw, unit); s != want {
+			t.Errorf("FormatSizeUnit(%d, %q) = %q, want %q", int64(raw), unit, s, want)
+		}
+	}
+}
+
+func TestParseBits(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"8", 8},
+		{"8 bits", 8},
+		{"100Mbit", 100000000},
+		{"100mbit", 100000000},
+		{"1Gbps", 1000000000},
+		{"1GBPS", 1000000000},
+		{"2.5 kbit", 2500},
+		{"1B", 8},
+		{"1b", 8},
+		{"1 byte", 8},
+		{"1KB", 8000},
+	}
+	for _, c := range cases {
+		bits, err := ParseBits(c.in)
+		if err != nil {
+			t.Errorf("ParseBits(%q) error: %v", c.in, err)
+			continue
+		}
+		if bits != c.want {
+			t.Errorf("ParseBits(%q) = %d, want %d", c.in, bits, c.want)
+		}
+	}
+	for _, in := range []string{"", "Mbit", "1 xbit", "1 kilobit"} {
+		if _, err := ParseBits(in); err == nil {
+			t.Errorf("ParseBits(%q) returned nil error", in)
+		}
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	cases := map[int64]string{
+		8:          "8.00bit",
+		1500:       "1.50kbit",
+		100000000:  "100.00Mbit",
+		1000000000: "1.00Gbit",
+	}
+	for bits, want := range cases {
+		s := FormatBits(bits)
+		if s != want {
+			t.Errorf("FormatBits(%d) = %q, want %q", bits, s, want)
+		}
+		if back, err := ParseBits(s); err != nil || back != bits {
+			t.Errorf("ParseBits(%q) = %d, %v, want %d", s, back, err, bits)
+		}
+	}
+}
+
+func TestParseSizeCompound(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"1G512M", 1610612736},
+		{"2T1G", 2199023255552 + 1073741824},
+		{"1G 512M 1K", 1610612736 + 1024},
+		{"512M", 536870912},
+		{"1024", 1024},
+	}
+	for _, c := range cases {
+		size, err := ParseSizeCompound(c.in)
+		if err != nil {
+			t.Errorf("ParseSizeCompound(%q) error: %v", c.in, err)
+			continue
+		}
+		if size != c.want {
+			t.Errorf("ParseSizeCompound(%q) = %d, want %d", c.in, size, c.want)
+		}
+	}
+	for _, in := range []string{"", "1M1M", "512M1G", "1G512X", "G512M"} {
+		if _, err := ParseSizeCompound(in); err == nil {
+			t.Errorf("ParseSizeCompound(%q) returned nil error", in)
+		}
+	}
+}
+
+func TestSizeGlob(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]int{
+		"a.log":   100,
+		"b.log":   20,
+		"c.txt":   5000,
+		"d.log.1": 300,
+	}
+	for name, n := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), make([]byte, n), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir.log"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	size, count, err := SizeGlob(filepath.Join(root, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 120 || count != 2 {
+		t.Errorf("SizeGlob = %d, %d, want 120, 2", size, count)
+	}
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "broken.log")); err != nil {
+		t.Fatal(err)
+	}
+	size, count, err = SizeGlob(filepath.Join(root, "*.log"))
+	if err == nil {
+		t.Error("SizeGlob with a broken match returned nil error")
+	}
+	if size != 120 || count != 2 {
+		t.Errorf("SizeGlob = %d, %d, want 120, 2", size, count)
+	}
+}
+
+func TestReadableSizeTrim(t *testing.T) {
+	cases := map[int64]string{
+		0:       "0B",
+		512:     "512B",
+		1536:    "1.5K",
+		4194304: "4M",
+		1572864: "1.5M",
+	}
+	for raw, want := range cases {
+		if s := FormatSizeTrim(raw); s != want {
+			t.Errorf("FormatSizeTrim(%d) = %q, want %q", raw, s, want)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "f")
+	if err := ioutil.WriteFile(path, make([]byte, 1536), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s := ReadableSizeTrim(path); s != "1.5K" {
+		t.Errorf("ReadableSizeTrim = %q, want %q", s, "1.5K")
+	}
+	if s := ReadableSize(path); s != "1.50K" {
+		t.Errorf("ReadableSize = %q, want %q", s, "1.50K")
+	}
+}
+
+func TestSizeOpt(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "target")
+	if err := ioutil.WriteFile(target, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if size := SizeOpt(link, true); size != 4096 {
+		t.Errorf("SizeOpt(link, true) = %d, want 4096", size)
+	}
+	if size := SizeOpt(link, false); size != int64(len(target)) {
+		t.Errorf("SizeOpt(link, false) = %d, want %d", size, len(target))
+	}
+	if size := Size(link); size != 4096 {
+		t.Errorf("Size(link) = %d, want 4096", size)
+	}
+}
+
+func TestFormatSizeRound(t *testing.T) {
+	cases := []struct {
+		raw               int64
+		nearest, up, down string
+	}{
+		// 1.430511474609375M
+		{1500000, "1.43M", "1.44M", "1.43M"},
+		// 1.9073486328125M
+		{2000000, "1.91M", "1.91M", "1.90M"},
+		// 1.099609375K
+		{1126, "1.10K", "1.10K", "1.09K"},
+		{1024, "1.00K", "1.00K", "1.00K"},
+		{1280, "1.25K", "1.25K", "1.25K"},
+		{-1500000, "-1.43M", "-1.44M", "-1.43M"},
+	}
+	for _, c := range cases {
+		if s := FormatSizeRound(c.raw, RoundNearest); s != c.nearest {
+			t.Errorf("FormatSizeRound(%d, RoundNearest) = %q, want %q", c.raw, s, c.nearest)
+		}
+		if s := FormatSizeRound(c.raw, RoundUp); s != c.up {
+			t.Errorf("FormatSizeRound(%d, RoundUp) = %q, want %q", c.raw, s, c.up)
+		}
+		if s := FormatSizeRound(c.raw, RoundDown); s != c.down {
+			t.Errorf("FormatSizeRound(%d, RoundDown) = %q, want %q", c.raw, s, c.down)
+		}
+	}
+}
+
+func TestFormatBytesGrouped(t *testing.T) {
+	cases := []struct {
+		raw  int64
+		sep  rune
+		want string
+	}{
+		{1048576, ',', "1,048,576"},
+		{1048576, ' ', "1 048 576"},
+		{999, ',', "999"},
+		{1000, ',', "1,000"},
+		{0, ',', "0"},
+		{-1048576, ',', "-1,048,576"},
+		{-512, ',', "-512"},
+		{123456, '.', "123.456"},
+	}
+	for _, c := range cases {
+		if s := FormatBytesGrouped(c.raw, c.sep); s != c.want {
+			t.Errorf("FormatBytesGrouped(%d, %q) = %q, want %q", c.raw, c.sep, s, c.want)
+		}
+	}
+}
+
+func TestSizeFormatter(t *testing.T) {
+	f := SizeFormatter{Base: 60, Units: []string{"s", "m", "h"}, Precision: -1, Space: true}
+	cases := map[int64]string{
+		45:     "45 s",
+		90:     "1.5 m",
+		7200:   "2 h",
+		864000: "240 h",
+		-90:    "-1.5 m",
+	}
+	for raw, want := range cases {
+		if s := f.Format(raw); s != want {
+			t.Errorf("Format(%d) = %q, want %q", raw, s, want)
+		}
+	}
+	if s := BinaryFormatter.Format(1572864); s != FormatSize(1572864) {
+		t.Errorf("BinaryFormatter.Format = %q, want %q", s, FormatSize(1572864))
+	}
+	if s := DecimalFormatter.Format(1500000); s != "1.50MB" {
+		t.Errorf("DecimalFormatter.Format = %q, want %q", s, "1.50MB")
+	}
+}
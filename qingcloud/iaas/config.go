@@ -0,0 +1,135 @@
+package iaas
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"utils/conv"
+	verror "utils/os/error"
+)
+
+const (
+	gDEFAULT_RETRY_BACKOFF = 100 * time.Millisecond // Default delay before the first retry.
+	gDEFAULT_EXPIRES       = 10 * time.Second       // Default validity of the signatures.
+)
+
+// Config is the configuration of sending requests to the IaaS server.
+type Config struct {
+	KeyID     string        // Access key ID.
+	SecretKey string        // Secret access key.
+	URI       string        // URI of the API, eg: /iaas/.
+	Host      string        // Host of the server.
+	Port      string        // Port of the server, the default port of Scheme is used if empty.
+	Scheme    string        // http or https, http by default.
+	Timeout   time.Duration // Timeout of a call including the retries, no timeout if zero.
+	Expires   time.Duration // Validity of the signature of each attempt, 10s by default.
+
+	Retries      int           // Number of retries of the 429 and 5xx responses, no retry by default.
+	RetryBackoff time.Duration // Delay before the first retry, which doubles for each retry, 100ms by default.
+
+	// Client sends the requests, eg: with a proxy. By default, http.DefaultClient sends
+	// the http requests, and a client not verifying the certificate sends the https ones.
+	Client *http.Client
+
+	// Logger is called with each request before sending it, eg: to trace the requests.
+	Logger func(req RequestInfo)
+}
+
+// check returns an error if a required field is missing.
+func (c Config) check() error {
+	var missing []string
+	if c.KeyID == "" {
+		missing = append(missing, "KeyID")
+	}
+	if c.SecretKey == "" {
+		missing = append(missing, "SecretKey")
+	}
+	if c.URI == "" {
+		missing = append(missing, "URI")
+	}
+	if c.Host == "" {
+		missing = append(missing, "Host")
+	}
+	if len(missing) > 0 {
+		return verror.Newf("invalid iaas config: %s required", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// scheme returns the scheme of the requests, which is http by default.
+func (c Config) scheme() string {
+	if scheme := strings.ToLower(c.Scheme); scheme != "" {
+		return scheme
+	}
+	return "http"
+}
+
+// client returns the client sending the requests.
+func (c Config) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	if c.scheme() == "https" {
+		return tlsClient
+	}
+	return http.DefaultClient
+}
+
+// configFromMap converts the <conf> map of Send to Config.
+// The URI is the entry of <uriKey> if given, else console_uri.
+func configFromMap(conf map[string]interface{}, uriKey ...string) (Config, error) {
+	key := "console_uri"
+	if len(uriKey) > 0 && uriKey[0] != "" {
+		key = uriKey[0]
+	}
+	config := Config{
+		KeyID:     conv.String(conf["console_key_id"]),
+		SecretKey: conv.String(conf["console_secrect_key"]),
+		URI:       conv.String(conf[key]),
+		Host:      conv.String(conf["host"]),
+		Port:      conv.String(conf["port"]),
+		Scheme:    conv.String(conf["protocol"]),
+		Retries:   conv.Int(conf["retries"]),
+	}
+	if client, ok := conf["client"]; ok && client != nil {
+		if config.Client, ok = client.(*http.Client); !ok {
+			return config, verror.Newf("invalid client: %T is not *http.Client", client)
+		}
+	}
+	if logger, ok := conf["logger"]; ok && logger != nil {
+		if config.Logger, ok = logger.(func(RequestInfo)); !ok {
+			return config, verror.Newf("invalid logger: %T is not func(iaas.RequestInfo)", logger)
+		}
+	}
+	var err error
+	if config.RetryBackoff, err = confDuration(conf, "retry_backoff"); err != nil {
+		return config, err
+	}
+	if config.Timeout, err = confDuration(conf, "timeout"); err != nil {
+		return config, err
+	}
+	if config.Expires, err = confDuration(conf, "expires"); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// confDuration returns the duration of <key> in <conf>, which is a time.Duration or
+// a string like "1.5s". It returns zero if the key is missing.
+func confDuration(conf map[string]interface{}, key string) (time.Duration, error) {
+	switch v := conf[key].(type) {
+	case nil:
+		return 0, nil
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, verror.Newf("invalid %s: %v", key, err)
+		}
+		return d, nil
+	default:
+		return 0, verror.Newf("invalid %s: %v is not a duration", key, v)
+	}
+}
@@ -0,0 +1,66 @@
+package iaas
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigFromMap(t *testing.T) {
+	conf := map[string]interface{}{
+		"console_key_id":      "QYACCESSKEYIDEXAMPLE",
+		"console_secrect_key": "SECRETACCESSKEY",
+		"console_uri":         "/iaas/",
+		"api_uri":             "/api/",
+		"host":                "api.qingcloud.com",
+		"port":                443,
+		"protocol":            "https",
+		"retries":             "2",
+		"timeout":             "30s",
+		"expires":             time.Minute,
+	}
+	config, err := configFromMap(conf, "api_uri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		KeyID:     "QYACCESSKEYIDEXAMPLE",
+		SecretKey: "SECRETACCESSKEY",
+		URI:       "/api/",
+		Host:      "api.qingcloud.com",
+		Port:      "443",
+		Scheme:    "https",
+		Timeout:   30 * time.Second,
+		Expires:   time.Minute,
+		Retries:   2,
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+
+	conf["timeout"] = 30
+	if _, err = configFromMap(conf); err == nil {
+		t.Error("expected an error of invalid timeout")
+	}
+	conf["timeout"] = nil
+	conf["logger"] = func(string) {}
+	if _, err = configFromMap(conf); err == nil {
+		t.Error("expected an error of invalid logger")
+	}
+}
+
+func TestSendMissingConfig(t *testing.T) {
+	// The entries were asserted to strings, which panicked if missing.
+	_, err := Send("GET", map[string]interface{}{}, map[string]interface{}{"console_uri": "/iaas/"})
+	if err == nil || !strings.Contains(err.Error(), "KeyID, SecretKey, Host") {
+		t.Errorf("Send with missing entries returned %v", err)
+	}
+	_, err = Send("GET", map[string]interface{}{}, map[string]interface{}{}, "api_uri")
+	if err == nil || !strings.Contains(err.Error(), "URI") {
+		t.Errorf("Send with a missing URI entry returned %v", err)
+	}
+	if _, err = (Config{}).Send("GET", nil); err == nil {
+		t.Error("Send with an empty Config returned no error")
+	}
+}
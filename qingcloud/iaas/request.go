@@ -0,0 +1,141 @@
+package iaas
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	verror "utils/os/error"
+)
+
+// HTTPError is returned if the IaaS server answers a request with a non-2xx status.
+type HTTPError struct {
+	StatusCode int
+	Status     string // Status line, eg: "500 Internal Server Error".
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("iaas request failed with status %s", e.Status)
+	}
+	return fmt.Sprintf("iaas request failed with status %s: %s", e.Status, e.Body)
+}
+
+// APIError is returned if the IaaS server rejects a request with a non-zero ret_code,
+// though the HTTP status is 200.
+type APIError struct {
+	Code    int    // ret_code of the response.
+	Message string // message of the response.
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("iaas request failed with ret_code %d: %s", e.Code, e.Message)
+}
+
+// checkRetCode returns an APIError if the ret_code of response <body> is not zero.
+// A body which is not a JSON object, or without ret_code, is not checked.
+func checkRetCode(body []byte) error {
+	var resp struct {
+		RetCode *int   `json:"ret_code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.RetCode == nil || *resp.RetCode == 0 {
+		return nil
+	}
+	return &APIError{Code: *resp.RetCode, Message: resp.Message}
+}
+
+// RequestInfo describes a request passed to Config.Logger.
+// The access key ID and the signature are redacted from the URL.
+type RequestInfo struct {
+	Method string
+	URL    string
+	Header map[string]string
+	Body   string
+}
+
+// Parameters redacted from the URL of RequestInfo.
+var redactedParams = map[string]bool{
+	"access_key_id": true,
+	"signature":     true,
+}
+
+// redactParams replaces the values of the redacted parameters of <urlParams> with "***".
+func redactParams(urlParams string) string {
+	parts := strings.Split(urlParams, "&")
+	for i, part := range parts {
+		if key := strings.SplitN(part, "=", 2)[0]; redactedParams[key] {
+			parts[i] = key + "=***"
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// tlsClient sends the https requests. Like the https helpers of package utils/net/http,
+// it does not verify the certificate of the server.
+var tlsClient = &http.Client{
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+}
+
+// bodyMethods are the supported request methods, and whether their parameters
+// are sent as JSON body, or else in the query.
+var bodyMethods = map[string]bool{
+	"get":    false,
+	"delete": false,
+	"head":   false,
+	"post":   true,
+	"put":    true,
+	"patch":  true,
+}
+
+// doRequest sends a request of <method> to <url> with <client>, and returns the body of the response.
+// The request is aborted when <ctx> is done.
+// The <body> is sent only for the methods in bodyMethods.
+func doRequest(ctx context.Context, client *http.Client, method, url, body string, headers map[string]string) ([]byte, error) {
+	hasBody, ok := bodyMethods[method]
+	if !ok {
+		return nil, verror.Newf("unsupported iaas request method: %s", method)
+	}
+	var reader io.Reader
+	if hasBody {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), url, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Add(key, value)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &HTTPError{StatusCode: res.StatusCode, Status: res.Status, Body: string(data)}
+	}
+	return data, nil
+}
+
+// decodeResponse decodes the JSON response <body>.
+// It returns the body as a string if it is not JSON.
+func decodeResponse(body []byte) interface{} {
+	var resp interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return string(body)
+	}
+	return resp
+}
@@ -0,0 +1,370 @@
+package iaas
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConf returns the conf of sending requests to <server>.
+func testConf(t *testing.T, server *httptest.Server) map[string]interface{} {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]interface{}{
+		"protocol":            u.Scheme,
+		"host":                host,
+		"port":                port,
+		"console_uri":         "/iaas/",
+		"console_key_id":      "QYACCESSKEYIDEXAMPLE",
+		"console_secrect_key": "SECRETACCESSKEY",
+	}
+}
+
+func TestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/iaas/" || r.URL.Query().Get("action") != "DescribeInstances" {
+			t.Errorf("request = %s %s", r.Method, r.URL)
+		}
+		w.Write([]byte(`{"action":"DescribeInstancesResponse","ret_code":0,"total_count":1}`))
+	}))
+	defer server.Close()
+
+	resp, err := Send("GET", map[string]interface{}{"action": "DescribeInstances"}, testConf(t, server))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := resp.(map[string]interface{}); !ok || m["total_count"] != float64(1) {
+		t.Errorf("response = %v", resp)
+	}
+}
+
+func TestSendHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	conf := testConf(t, server)
+
+	_, err := Send("POST", map[string]interface{}{"action": "RunInstances"}, conf)
+	if e, ok := err.(*HTTPError); !ok || e.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Send to a failing server returned %v, want an HTTPError", err)
+	}
+
+	// The server is not listening anymore.
+	server.Close()
+	if _, err = Send("GET", map[string]interface{}{"action": "DescribeInstances"}, conf); err == nil {
+		t.Error("Send to a closed server returned no error")
+	}
+}
+
+func TestSendRetry(t *testing.T) {
+	var (
+		attempts   int
+		signatures = make(map[string]bool)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		signatures[r.URL.Query().Get("signature")] = true
+		if attempts <= 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ret_code":0}`))
+	}))
+	defer server.Close()
+	conf := testConf(t, server)
+
+	// No retry by default.
+	if _, err := Send("GET", map[string]interface{}{"action": "DescribeZones"}, conf); err == nil || attempts != 1 {
+		t.Errorf("Send without retries = %v after %d attempts", err, attempts)
+	}
+
+	attempts, signatures = 0, make(map[string]bool)
+	conf["retries"] = 3
+	conf["retry_backoff"] = "1ms"
+	// Each attempt is signed one second later.
+	start := time.Now()
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time {
+		return start.Add(time.Duration(attempts) * time.Second)
+	}
+	params := map[string]interface{}{"action": "DescribeZones"}
+	if _, err := Send("GET", params, conf); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+	if len(signatures) != 3 {
+		t.Errorf("the requests were not signed again: %v", signatures)
+	}
+
+	conf["retry_backoff"] = 10
+	if _, err := Send("GET", params, conf); err == nil {
+		t.Error("expected an error of invalid retry_backoff")
+	}
+}
+
+func TestSendCtx(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+	conf := testConf(t, server)
+	params := map[string]interface{}{"action": "DescribeZones"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := SendCtx(ctx, "GET", params, conf); err == nil {
+		t.Error("SendCtx to a hung server returned no error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SendCtx returned after %v", elapsed)
+	}
+
+	conf["timeout"] = "50ms"
+	start = time.Now()
+	if _, err := Send("GET", params, conf); err == nil {
+		t.Error("Send with a timeout to a hung server returned no error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send with a timeout returned after %v", elapsed)
+	}
+}
+
+func TestSendInto(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"action": "DescribeInstancesResponse",
+			"instance_set": [
+				{"instance_id": "i-1234abcd", "instance_name": "web01", "status": "running", "vcpus_current": 2}
+			],
+			"ret_code": 0,
+			"total_count": 1
+		}`))
+	}))
+	defer server.Close()
+
+	var resp struct {
+		Action      string `json:"action"`
+		InstanceSet []struct {
+			InstanceID   string `json:"instance_id"`
+			InstanceName string `json:"instance_name"`
+			Status       string `json:"status"`
+			VCPUs        int    `json:"vcpus_current"`
+		} `json:"instance_set"`
+		TotalCount int `json:"total_count"`
+	}
+	err := SendInto("GET", map[string]interface{}{"action": "DescribeInstances"}, testConf(t, server), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Action != "DescribeInstancesResponse" || resp.TotalCount != 1 || len(resp.InstanceSet) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if i := resp.InstanceSet[0]; i.InstanceID != "i-1234abcd" || i.InstanceName != "web01" || i.Status != "running" || i.VCPUs != 2 {
+		t.Errorf("instance = %+v", i)
+	}
+
+	var n int
+	if err = SendInto("GET", map[string]interface{}{"action": "DescribeInstances"}, testConf(t, server), &n); err == nil {
+		t.Error("SendInto of an object into an int returned no error")
+	}
+}
+
+func TestSendLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ret_code":0}`))
+	}))
+	defer server.Close()
+
+	var logged []RequestInfo
+	conf := testConf(t, server)
+	conf["logger"] = func(req RequestInfo) {
+		logged = append(logged, req)
+	}
+	if _, err := Send("POST", map[string]interface{}{"action": "RunInstances"}, conf); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 1 {
+		t.Fatalf("logged %d requests, want 1", len(logged))
+	}
+	req := logged[0]
+	if req.Method != "POST" || req.Body != `{"action":"RunInstances"}` || req.Header["Content-Type"] != "application/json" {
+		t.Errorf("logged request = %+v", req)
+	}
+	if !strings.HasPrefix(req.URL, server.URL+"/iaas/?") {
+		t.Errorf("logged URL = %q", req.URL)
+	}
+	if strings.Contains(req.URL, "QYACCESSKEYIDEXAMPLE") ||
+		!strings.Contains(req.URL, "access_key_id=***") || !strings.Contains(req.URL, "signature=***") {
+		t.Errorf("logged URL = %q, want the key ID and the signature redacted", req.URL)
+	}
+}
+
+func TestSendAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ret_code":1400,"message":"PermissionDenied, access key not found"}`))
+	}))
+	defer server.Close()
+
+	resp, err := Send("GET", map[string]interface{}{"action": "DescribeInstances"}, testConf(t, server))
+	e, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("Send returned %v, %v, want an APIError", resp, err)
+	}
+	if e.Code != 1400 || e.Message != "PermissionDenied, access key not found" {
+		t.Errorf("APIError = %+v", e)
+	}
+	var out map[string]interface{}
+	if err = SendInto("GET", map[string]interface{}{"action": "DescribeInstances"}, testConf(t, server), &out); err == nil {
+		t.Error("SendInto returned no error for a non-zero ret_code")
+	}
+}
+
+func TestCheckRetCode(t *testing.T) {
+	for _, body := range []string{`{"ret_code":0}`, `{"total_count":1}`, `[1,2]`, `not json`, ``} {
+		if err := checkRetCode([]byte(body)); err != nil {
+			t.Errorf("checkRetCode(%s) = %v, want nil", body, err)
+		}
+	}
+}
+
+func TestSendClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ret_code":0,"total_count":3}`))
+	}))
+	defer server.Close()
+
+	// The client of the test server trusts its certificate.
+	client := server.Client()
+	var requests int
+	transport := client.Transport
+	client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return transport.RoundTrip(r)
+	})
+	conf := testConf(t, server)
+	conf["client"] = client
+	resp, err := Send("GET", map[string]interface{}{"action": "DescribeInstances"}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := resp.(map[string]interface{}); !ok || m["total_count"] != float64(3) {
+		t.Errorf("response = %v", resp)
+	}
+	if requests != 1 {
+		t.Errorf("the injected client sent %d requests, want 1", requests)
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSendMethods(t *testing.T) {
+	var (
+		method string
+		query  url.Values
+		body   string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		method, query, body = r.Method, r.URL.Query(), string(data)
+		w.Write([]byte(`{"ret_code":0}`))
+	}))
+	defer server.Close()
+	conf := testConf(t, server)
+
+	if _, err := Send("PATCH", map[string]interface{}{"instance": "i-1234abcd"}, conf); err != nil {
+		t.Fatal(err)
+	}
+	if method != "PATCH" || body != `{"instance":"i-1234abcd"}` || query.Get("instance") != "" || query.Get("signature") == "" {
+		t.Errorf("PATCH request = %s %v %s", method, query, body)
+	}
+
+	resp, err := Send("head", map[string]interface{}{"instance": "i-1234abcd"}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != "HEAD" || body != "" || query.Get("instance") != "i-1234abcd" || resp != nil {
+		t.Errorf("HEAD request = %s %v %s, response = %v", method, query, body, resp)
+	}
+
+	method = ""
+	if _, err = Send("OPTIONS", map[string]interface{}{}, conf); err == nil || !strings.Contains(err.Error(), "OPTIONS") {
+		t.Errorf("Send with an unknown method returned %v", err)
+	}
+	if method != "" {
+		t.Errorf("a %s request was sent for an unknown method", method)
+	}
+}
+
+func TestSendBodyHeaders(t *testing.T) {
+	var (
+		length      int64
+		contentType string
+		body        string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		length, contentType, body = r.ContentLength, r.Header.Get("Content-Type"), string(data)
+		w.Write([]byte(`{"ret_code":0}`))
+	}))
+	defer server.Close()
+
+	// The multi-byte description makes the byte count differ from the character count.
+	if _, err := Send("POST", map[string]interface{}{"description": "数据"}, testConf(t, server)); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"description":"数据"}` {
+		t.Errorf("body = %q", body)
+	}
+	if length != int64(len(body)) {
+		t.Errorf("received Content-Length = %d, want %d", length, len(body))
+	}
+	if contentType != "application/json" {
+		t.Errorf("received Content-Type = %q, want %q", contentType, "application/json")
+	}
+}
+
+func TestSendURI(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/iaas/" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		query = r.URL.Query()
+		w.Write([]byte(`{"ret_code":0}`))
+	}))
+	defer server.Close()
+
+	conf := testConf(t, server)
+	conf["console_uri"] = "/api/v1/iaas/?zone=pek3"
+	if _, err := Send("GET", map[string]interface{}{"action": "DescribeInstances", "search_word": "web 01"}, conf); err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("zone") != "pek3" || query.Get("action") != "DescribeInstances" ||
+		query.Get("search_word") != "web 01" || query.Get("signature") == "" {
+		t.Errorf("query = %v", query)
+	}
+}
@@ -1,75 +1,204 @@
 package iaas
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"reflect"
 	"sort"
 	"strings"
 	"time"
 	"utils/conv"
-	vhttp "utils/net/http"
 	verror "utils/os/error"
 	qcutil "utils/qingcloud"
 	"utils/util"
 )
 
+// timeNow returns the time stamp of the signatures, which is replaced in tests.
+var timeNow = time.Now
+
 // Send 发送请求到Iaas
-// conf 包含配置：console_key_id,console_secrect_key,console_uri,host,port
+// conf 包含配置：console_key_id,console_secrect_key,console_uri,host,port,protocol
+// protocol 为 http 或 https，默认为 http；port 为空时使用协议的默认端口
+// retries 为 429 及 5xx 响应的重试次数，默认不重试；retry_backoff 为首次重试前的等待时间，
+// 如 "200ms"，默认 100ms，之后每次加倍。每次重试都重新签名。
+// timeout 为包括重试在内的超时时间，如 "30s"，默认不超时。
+// expires 为签名的有效期，如 "1m"，默认 10 秒。
+//
+// Deprecated: 请使用 Config.Send
 func Send(method string, params map[string]interface{}, conf map[string]interface{}, uriKey ...string) (interface{}, error) {
+	return SendCtx(context.Background(), method, params, conf, uriKey...)
+}
+
+// SendCtx 同 Send，在 ctx 结束时立即中止请求并返回 ctx.Err()
+//
+// Deprecated: 请使用 Config.SendCtx
+func SendCtx(ctx context.Context, method string, params map[string]interface{}, conf map[string]interface{}, uriKey ...string) (interface{}, error) {
+	config, err := configFromMap(conf, uriKey...)
+	if err != nil {
+		return nil, err
+	}
+	return config.SendCtx(ctx, method, params)
+}
+
+// SendInto 同 Send，将 JSON 响应解码到 out
+func SendInto(method string, params map[string]interface{}, conf map[string]interface{}, out interface{}, uriKey ...string) error {
+	config, err := configFromMap(conf, uriKey...)
+	if err != nil {
+		return err
+	}
+	return config.SendInto(method, params, out)
+}
+
+// Send 发送请求到Iaas，参数及返回值同 SendCtx
+func (c Config) Send(method string, params map[string]interface{}) (interface{}, error) {
+	return c.SendCtx(context.Background(), method, params)
+}
+
+// SendCtx 发送请求到Iaas，返回解码后的 JSON 响应
+// method 为 get、delete、head、post、put 或 patch，其中 post、put、patch 的参数以 JSON 请求体发送
+// 响应的 ret_code 不为 0 时返回 *APIError
+// 429 及 5xx 响应按 Retries 重试，每次重试都重新签名；在 ctx 结束时立即中止请求并返回 ctx.Err()
+func (c Config) SendCtx(ctx context.Context, method string, params map[string]interface{}) (interface{}, error) {
+	body, err := c.do(ctx, method, params)
+	if err != nil || len(body) == 0 {
+		return nil, err
+	}
+	return decodeResponse(body), nil
+}
+
+// SendInto 同 Config.Send，将 JSON 响应解码到 out
+func (c Config) SendInto(method string, params map[string]interface{}, out interface{}) error {
+	return c.SendIntoCtx(context.Background(), method, params, out)
+}
+
+// SendIntoCtx 同 Config.SendCtx，将 JSON 响应解码到 out
+func (c Config) SendIntoCtx(ctx context.Context, method string, params map[string]interface{}, out interface{}) error {
+	body, err := c.do(ctx, method, params)
+	if err != nil || len(body) == 0 {
+		return err
+	}
+	if err = json.Unmarshal(body, out); err != nil {
+		return verror.Newf("invalid iaas response: %v", err)
+	}
+	return nil
+}
+
+// do sends a request of <method>, retrying it on transient failures,
+// and returns the body of the response.
+func (c Config) do(ctx context.Context, method string, params map[string]interface{}) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
 	_method := strings.ToLower(method)
-	_uriKey := conf["console_uri"].(string)
-	if len(uriKey) > 0 && uriKey[0] != "" {
-		_uriKey = conf[uriKey[0]].(string)
+	if _, ok := bodyMethods[_method]; !ok {
+		return nil, verror.Newf("unsupported iaas request method: %s", method)
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	backoff := c.RetryBackoff
+	if backoff <= 0 {
+		backoff = gDEFAULT_RETRY_BACKOFF
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, err := c.send(ctx, _method, params)
+		if err == nil {
+			return body, checkRetCode(body)
+		}
+		if attempt >= c.Retries || !isRetryable(err) {
+			return nil, err
+		}
+		select {
+		case <-time.After(backoff << uint(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// send signs and sends a request of <method>, and returns the body of the response.
+// As the signature expires soon, it should be called for each attempt.
+// The query of the URI, if any, is sent but not signed.
+func (c Config) send(ctx context.Context, method string, params map[string]interface{}) ([]byte, error) {
+	uri, err := url.Parse(c.URI)
+	if err != nil {
+		return nil, verror.Newf("invalid iaas URI %q: %v", c.URI, err)
 	}
-	urlParams, _, data, err := Signature(_method, _uriKey, conf["console_key_id"].(string), conf["console_secrect_key"].(string), params)
+	urlParams, _, data, err := Signature(method, uri.Path, c.KeyID, c.SecretKey, params, c.Expires)
 	if err != nil {
 		return nil, err
 	}
 
+	headers := requestHeaders(method)
+	if c.Logger != nil {
+		c.Logger(RequestInfo{
+			Method: strings.ToUpper(method),
+			URL:    requestURL(c.scheme(), c.Host, c.Port, uri, redactParams(urlParams)),
+			Header: headers,
+			Body:   data,
+		})
+	}
+	return doRequest(ctx, c.client(), method, requestURL(c.scheme(), c.Host, c.Port, uri, urlParams), data, headers)
+}
+
+// isRetryable checks whether the request failed with a transient status, which is 429 or 5xx.
+func isRetryable(err error) bool {
+	e, ok := err.(*HTTPError)
+	return ok && (e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500)
+}
+
+// requestHeaders returns the headers of a <method> request, which sends a JSON body
+// for the methods with body. The Content-Length is set by the request from the body.
+func requestHeaders(method string) map[string]string {
 	headers := map[string]string{}
-	if _method == "post" || _method == "put" {
-		headers["Content-Type"] = "'application/x-www-form-urlencoded'"
+	if bodyMethods[method] {
+		headers["Content-Type"] = "application/json"
 		headers["Accept"] = "text/plain"
 		headers["Connection"] = "Keep-Alive"
-		headers["Content-Length"] = string(len(data))
 	}
+	return headers
+}
 
-	var url string = fmt.Sprintf(conf["protocol"].(string)+"://%s:%s%s", conf["host"].(string), conf["port"].(string), _uriKey)
-
-	var resp interface{}
-	if conf["protocol"].(string) == "https" {
-		if _method == "get" {
-			vhttp.TLSGet(url+"?"+urlParams, &resp, headers)
-		} else if _method == "post" {
-			vhttp.TLSPost(url+"?"+urlParams, data, &resp, headers)
-		} else if _method == "put" {
-			vhttp.TLSPut(url+"?"+urlParams, data, &resp, headers)
-		} else if _method == "delete" {
-			vhttp.TLSDelete2(url+"?"+urlParams, &resp, headers)
+// requestURL returns the URL of <uri> on server <host>, with the query parameters of <uri>
+// followed by the escaped <urlParams>. The port is omitted if empty, so that the default port
+// of <scheme> is used, or if <host> contains a port.
+func requestURL(scheme, host, port string, uri *url.URL, urlParams string) string {
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     uri.Path,
+		RawQuery: uri.RawQuery,
+	}
+	if port != "" {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			u.Host = net.JoinHostPort(strings.Trim(host, "[]"), port)
 		}
-	} else {
-		if _method == "get" {
-			vhttp.Get2(url+"?"+urlParams, &resp, headers)
-		} else if _method == "post" {
-			vhttp.Post2(url+"?"+urlParams, data, &resp, headers)
-		} else if _method == "put" {
-			vhttp.Put(url+"?"+urlParams, data, &resp, headers)
-		} else if _method == "delete" {
-			vhttp.Delete(url+"?"+urlParams, &resp, headers)
+	}
+	if urlParams != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
 		}
+		u.RawQuery += urlParams
 	}
-
-	return resp, nil
+	return u.String()
 }
 
-func Signature(method, uri, ak, sk string, params map[string]interface{}) (string, string, string, error) {
+// Signature 签名请求参数，返回签名后的 url 参数、签名及请求体
+// expires 为签名的有效期，默认 10 秒
+func Signature(method, uri, ak, sk string, params map[string]interface{}, expires ...time.Duration) (string, string, string, error) {
 	_method := strings.ToLower(method)
 	// _params := url.Values{}
 	_params := map[string]interface{}{}
 
 	var _data string = ""
-	if _method == "get" || _method == "delete" {
+	if hasBody, ok := bodyMethods[_method]; ok && !hasBody {
 		_params = params
 	} else {
 		bData, err := json.Marshal(params)
@@ -80,9 +209,13 @@ func Signature(method, uri, ak, sk string, params map[string]interface{}) (strin
 	}
 
 	// time_stamp := time.Now() //time.Now().UTC().Format(time.RFC3339)
-	time_stamp := time.Now()
-	_params["time_stamp"] = util.TimeToString(time_stamp, "ISO 8601")                  // TimeToString(time_stamp, "ISO 8601")
-	_params["expires"] = util.TimeToString(time_stamp.Add(10*time.Second), "ISO 8601") // time.Now().Add(time.Hour).Format("2006-01-02T15:04:05Z")
+	time_stamp := timeNow()
+	_params["time_stamp"] = util.TimeToString(time_stamp, "ISO 8601") // TimeToString(time_stamp, "ISO 8601")
+	expiry := gDEFAULT_EXPIRES
+	if len(expires) > 0 && expires[0] > 0 {
+		expiry = expires[0]
+	}
+	_params["expires"] = util.TimeToString(time_stamp.Add(expiry), "ISO 8601") // time.Now().Add(time.Hour).Format("2006-01-02T15:04:05Z")
 	_params["signature_version"] = "1"
 	_params["signature_method"] = "HmacSHA256"
 	_params["access_key_id"] = ak
@@ -0,0 +1,71 @@
+package iaas
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRequestURL(t *testing.T) {
+	tests := []struct {
+		config Config
+		uri    string
+		url    string
+	}{
+		{Config{Host: "api.qingcloud.com", Port: "7777"}, "/iaas/", "http://api.qingcloud.com:7777/iaas/?action=RunInstances"},
+		{Config{Host: "api.qingcloud.com", Port: "443", Scheme: "https"}, "/iaas/", "https://api.qingcloud.com:443/iaas/?action=RunInstances"},
+		{Config{Host: "api.qingcloud.com", Scheme: "HTTPS"}, "/iaas/", "https://api.qingcloud.com/iaas/?action=RunInstances"},
+		{Config{Host: "api.qingcloud.com:7777", Port: "7777"}, "/iaas/", "http://api.qingcloud.com:7777/iaas/?action=RunInstances"},
+		{Config{Host: "::1", Port: "7777"}, "/iaas/", "http://[::1]:7777/iaas/?action=RunInstances"},
+		{Config{Host: "api.qingcloud.com"}, "/api/v1/iaas/", "http://api.qingcloud.com/api/v1/iaas/?action=RunInstances"},
+		{Config{Host: "api.qingcloud.com"}, "/iaas/?zone=pek3", "http://api.qingcloud.com/iaas/?zone=pek3&action=RunInstances"},
+		{Config{Host: "api.qingcloud.com"}, "/iaas v1/", "http://api.qingcloud.com/iaas%20v1/?action=RunInstances"},
+	}
+	for _, tt := range tests {
+		uri, err := url.Parse(tt.uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u := requestURL(tt.config.scheme(), tt.config.Host, tt.config.Port, uri, "action=RunInstances"); u != tt.url {
+			t.Errorf("URL of %q on %+v = %q, want %q", tt.uri, tt.config, u, tt.url)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	if h := requestHeaders("post"); h["Content-Type"] != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", h["Content-Type"], "application/json")
+	}
+	if h := requestHeaders("get"); len(h) != 0 {
+		t.Errorf("headers of get = %v, want none", h)
+	}
+}
+
+func TestSignatureExpires(t *testing.T) {
+	stamp := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time { return stamp }
+
+	tests := []struct {
+		expires []time.Duration
+		want    string
+	}{
+		{nil, "2021-03-04T05:06:17Z"},
+		{[]time.Duration{0}, "2021-03-04T05:06:17Z"},
+		{[]time.Duration{time.Minute}, "2021-03-04T05:07:07Z"},
+	}
+	for _, tt := range tests {
+		urlParams, _, _, err := Signature("GET", "/iaas/", "QYACCESSKEYIDEXAMPLE", "SECRETACCESSKEY", map[string]interface{}{}, tt.expires...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query, err := url.ParseQuery(urlParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query.Get("time_stamp") != "2021-03-04T05:06:07Z" || query.Get("expires") != tt.want {
+			t.Errorf("expires %v: time_stamp = %s, expires = %s, want %s",
+				tt.expires, query.Get("time_stamp"), query.Get("expires"), tt.want)
+		}
+	}
+}